@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+// Exit codes
+const (
+	exitOK      = 0 // success
+	exitFailure = 1 // the command ran and failed
+	exitUsage   = 2 // invalid command line
+	exitNoMatch = 3 // query or show found nothing
+)
+
+// errNoMatch makes a command exit with exitNoMatch.
+var errNoMatch = errors.New("no matches")
+
+// usageError makes a command print its usage and exit with exitUsage.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// globals are the flags every command accepts, before or after its name.
+type globals struct {
+	configPath string
+	quiet      bool
+	json       bool
+}
+
+func (g *globals) register(flags *flag.FlagSet) {
+	flags.StringVar(&g.configPath, "config", g.configPath, "path to the config file (default: the nearest "+config.ConfigFile+" in the target directory or its parents)")
+	flags.BoolVar(&g.quiet, "quiet", g.quiet, "print only results and errors")
+	flags.BoolVar(&g.json, "json", g.json, "print results as JSON")
+}
+
+// loadConfig reads the config named by --config, or the codemap.json found
+// in dir or its parents, and scopes it to dir. Without a config, every
+// supported language below dir is mapped with the default ignores; nothing
+// is written (see astrmap init).
+func (g *globals) loadConfig(dir string) (config.Config, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return config.Config{}, err
+	}
+	path := g.configPath
+	if path == "" {
+		path, err = config.Find(abs)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("ℹ️ No %s in %s or its parents: mapping all supported languages. Run 'astrmap init' to customize.", config.ConfigFile, abs)
+			cfg := config.Default(abs, mapper.RegisteredExts())
+			cfg.Scope = abs
+			return cfg, nil
+		}
+		if err != nil {
+			return config.Config{}, err
+		}
+	}
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		return config.Config{}, err
+	}
+	cfg.Scope = abs
+	if len(cfg.WalkRoots()) == 0 {
+		return config.Config{}, fmt.Errorf("%s is outside every root of %s", abs, cfg.File)
+	}
+	return cfg, nil
+}
+
+// infof prints progress for humans: never with --quiet or --json.
+func (g *globals) infof(format string, args ...any) {
+	if !g.quiet && !g.json {
+		fmt.Printf(format, args...)
+	}
+}
+
+// emit prints a command's result: v as JSON with --json, otherwise text.
+func (g *globals) emit(v any, text func()) error {
+	if g.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text()
+	return nil
+}
+
+// command is one astrmap subcommand.
+type command struct {
+	name    string
+	args    string // positional arguments, e.g. "[directory]"
+	summary string
+	// setup registers the command's flags and returns the function that
+	// runs it with the positional arguments.
+	setup func(flags *flag.FlagSet, g *globals) func(args []string) error
+}
+
+func (c *command) usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintf(w, "Usage: astrmap %s [flags] %s\n\n%s\n\nFlags:\n", c.name, c.args, c.summary)
+	flags.PrintDefaults()
+}
+
+// runCLI runs the command line args (without the program name) and returns
+// the exit code.
+func runCLI(args []string) int {
+	var g globals
+	top := flag.NewFlagSet("astrmap", flag.ContinueOnError)
+	top.SetOutput(os.Stderr)
+	g.register(top)
+	top.Usage = func() { printUsage(top.Output()) }
+	if err := top.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if top.NArg() == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	name, rest := top.Arg(0), top.Args()[1:]
+	if name == "help" {
+		return runHelp(rest)
+	}
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	run := cmd.setup(flags, &g)
+	g.register(flags)
+	flags.Usage = func() { cmd.usage(flags) }
+	positional, err := parseInterspersed(flags, rest)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if g.quiet {
+		log.SetOutput(io.Discard)
+	}
+	err = run(positional)
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errNoMatch):
+		return exitNoMatch
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		cmd.usage(flags)
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return exitFailure
+	}
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// the positional arguments, which it returns. "--" ends flag parsing.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		// Parse stops at the first non-flag, or right after a "--" it consumed
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// maxArgs fails with a usage error when more than n positional args are given.
+func maxArgs(args []string, n int) error {
+	if len(args) > n {
+		return usageError(fmt.Sprintf("unexpected arguments: %s", strings.Join(args[n:], " ")))
+	}
+	return nil
+}
+
+// argOr returns args[0], or def when there is none.
+func argOr(args []string, def string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return def
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// runHelp prints the usage of one command, or of astrmap.
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
+		return exitUsage
+	}
+	var g globals
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	cmd.setup(flags, &g)
+	g.register(flags)
+	cmd.usage(flags)
+	return exitOK
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "🗺️  AstrMap - The AST Indexer for LLMs")
+	fmt.Fprintln(w, "\nUsage: astrmap [--config file] [--quiet] [--json] <command> [flags] [args]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'astrmap help <command>' or 'astrmap <command> --help' for its flags.")
+	fmt.Fprintln(w, "\nExit codes: 0 ok, 1 failure, 2 invalid usage, 3 nothing found.")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/graph"
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+func graphCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	format := flags.String("format", "dot", "output format: dot, mermaid or json")
+	scope := flags.String("scope", "", "only draw the imports of the files below this directory")
+	dirs := flags.Bool("dirs", false, "draw one node per directory (package) instead of per file")
+	depth := flags.Int("depth", 0, "draw one node per directory this many levels below the --scope folder (or the root), with everything below it")
+	return func(args []string) error {
+		if err := maxArgs(args, 1); err != nil {
+			return err
+		}
+		switch *format {
+		case "dot", "mermaid", "json":
+		default:
+			return usageError(fmt.Sprintf("unknown format: %s (want dot, mermaid or json)", *format))
+		}
+		if *depth < 0 {
+			return usageError("--depth must not be negative")
+		}
+		return runGraph(g, argOr(args, "."), *format, *scope, graph.ViewOptions{Dirs: *dirs, Depth: *depth})
+	}
+}
+
+// runGraph prints the dependency graph of the files below targetDir.
+func runGraph(g *globals, targetDir, format, scope string, opts graph.ViewOptions) error {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	if info, err := os.Stat(absTarget); err != nil || !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", targetDir)
+	}
+	if scope != "" {
+		if opts.Scope, err = scopePath(absTarget, scope); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := g.loadConfig(absTarget)
+	if err != nil {
+		return err
+	}
+	deps, errs := graph.Build(cfg, absTarget, mapper.CollectFiles(cfg))
+	for _, err := range errs {
+		log.Printf("❌ %v", err)
+	}
+
+	view := deps.View(opts)
+	log.Printf("🔗 %d nodes, %d edges", len(view.Nodes), len(view.Edges))
+	if format == "json" {
+		g.json = true
+	}
+	return g.emit(view, func() {
+		if format == "mermaid" {
+			fmt.Print(view.Mermaid())
+		} else {
+			fmt.Print(view.DOT())
+		}
+	})
+}
+
+// scopePath returns the slash path of scope relative to root. scope is
+// relative to root or to the working directory, and must be inside root.
+func scopePath(root, scope string) (string, error) {
+	candidates := []string{filepath.Join(root, scope)}
+	if abs, err := filepath.Abs(scope); err == nil {
+		candidates = append(candidates, abs)
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, c)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return filepath.ToSlash(rel), nil
+	}
+	return "", fmt.Errorf("scope %s is not a path inside %s", scope, root)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+	"github.com/hubby247/astrmap/pkg/fs"
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+func initCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	yes := flags.Bool("yes", false, "accept the detected settings without prompting")
+	force := flags.Bool("force", false, "overwrite an existing "+config.ConfigFile)
+	exts := flags.String("exts", "", "comma-separated extensions to map instead of the detected ones")
+	ignore := flags.String("ignore", "", "comma-separated folder names to ignore instead of the proposed ones")
+	format := flags.String("format", "", "map output format to configure: text, json or both")
+	out := flags.String("out", "", "output directory to configure (e.g. "+config.DefaultOutDir+")")
+	return func(args []string) error {
+		if err := maxArgs(args, 1); err != nil {
+			return err
+		}
+		if *format != "" && !config.ValidFormat(*format) {
+			return usageError(fmt.Sprintf("unknown format: %s (want text, json or both)", *format))
+		}
+		return runInit(g, argOr(args, "."), initOptions{
+			yes: *yes, force: *force,
+			exts: fs.SplitList(*exts), ignores: fs.SplitList(*ignore),
+			format: *format, out: *out,
+		})
+	}
+}
+
+type initOptions struct {
+	yes, force    bool
+	exts, ignores []string // overrides of the detected values
+	format, out   string
+}
+
+// runInit writes a codemap.json to targetDir for the languages found in it.
+func runInit(g *globals, targetDir string, opts initOptions) error {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	if info, err := os.Stat(absTarget); err != nil || !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", targetDir)
+	}
+	if err := mapper.CheckOutDir(config.Config{Roots: []config.RootConfig{{Path: absTarget}}, OutDir: opts.out}); err != nil {
+		return usageError(err.Error())
+	}
+	path := g.configPath
+	if path == "" {
+		path = filepath.Join(absTarget, config.ConfigFile)
+	}
+	if _, err := os.Stat(path); err == nil && !opts.force {
+		return fmt.Errorf("%s already exists (use --force to overwrite it)", path)
+	}
+
+	g.infof("🔍 Detecting languages in %s...\n", absTarget)
+	d := config.Detect(absTarget, mapper.RegisteredExts())
+	for _, ext := range d.Exts {
+		g.infof("   %-8s %d files\n", ext, d.Counts[ext])
+	}
+
+	exts, ignores := d.Exts, d.Ignores
+	if opts.exts != nil {
+		exts = normalizeExts(opts.exts)
+	}
+	if opts.ignores != nil {
+		ignores = opts.ignores
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	if !opts.yes {
+		if !isTerminal(os.Stdin) {
+			return errors.New("stdin is not a terminal: pass --yes to accept the detected settings")
+		}
+		exts = normalizeExts(prompt(in, "Extensions", exts))
+		ignores = prompt(in, "Ignored folders", ignores)
+	}
+	if len(exts) == 0 {
+		return fmt.Errorf("no supported files found in %s (pass --exts to choose extensions)", absTarget)
+	}
+
+	// The root is relative to the config, so the project can be moved
+	root, err := filepath.Rel(filepath.Dir(mustAbs(path)), absTarget)
+	if err != nil {
+		root = absTarget
+	}
+	cfg := config.Config{
+		Roots:  []config.RootConfig{{Path: filepath.ToSlash(root), AllowedExts: exts, IgnoredDirs: ignores}},
+		Format: opts.format,
+		OutDir: opts.out,
+	}
+
+	if !opts.yes && !confirm(in, fmt.Sprintf("Write %s?", path)) {
+		return errors.New("aborted")
+	}
+	if err := config.Save(path, cfg); err != nil {
+		return err
+	}
+	return g.emit(cfg, func() {
+		g.infof("✅ Wrote %s (%s). Run 'astrmap scan' to map the project.\n", path, strings.Join(exts, ", "))
+	})
+}
+
+// prompt shows the current values and returns the comma-separated answer,
+// or the values unchanged on an empty answer.
+func prompt(in *bufio.Reader, label string, values []string) []string {
+	fmt.Printf("%s [%s]: ", label, strings.Join(values, ","))
+	line, _ := in.ReadString('\n')
+	if line = strings.TrimSpace(line); line == "" {
+		return values
+	}
+	return fs.SplitList(line)
+}
+
+// confirm asks a yes/no question that defaults to yes.
+func confirm(in *bufio.Reader, question string) bool {
+	fmt.Printf("%s [Y/n]: ", question)
+	line, _ := in.ReadString('\n')
+	line = strings.ToLower(strings.TrimSpace(line))
+	return line == "" || line == "y" || line == "yes"
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// normalizeExts lowercases extensions and adds the leading dot.
+func normalizeExts(exts []string) []string {
+	out := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		out = append(out, ext)
+	}
+	return out
+}
+
+func mustAbs(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -8,109 +9,233 @@ import (
 	"time"
 
 	"github.com/hubby247/astrmap/pkg/config"
-	"github.com/hubby247/astrmap/pkg/fs"
+	"github.com/hubby247/astrmap/pkg/graph"
 	"github.com/hubby247/astrmap/pkg/mapper"
 )
 
+// commands lists every subcommand, in the order of the usage text.
+var commands = []command{
+	{name: "init", args: "[directory]", summary: "Detect languages and write a " + config.ConfigFile, setup: initCommand},
+	{name: "scan", args: "[directory]", summary: "Scan directory and generate .map.txt / .map.json files", setup: scanCommand},
+	{name: "watch", args: "[directory]", summary: "Keep maps up to date while files change", setup: watchCommand},
+	{name: "query", args: "<pattern>", summary: "Search symbol names across all maps", setup: queryCommand},
+	{name: "show", args: "<file#Name | file:start-end>", summary: "Print the source lines of a mapped region", setup: showCommand},
+	{name: "pack", args: "[directory]", summary: "Print one map of the workspace that fits a token budget", setup: packCommand},
+	{name: "graph", args: "[directory]", summary: "Print the dependency graph as DOT, Mermaid or JSON", setup: graphCommand},
+	{name: "mcp", args: "[directory]", summary: "Serve maps to LLM agents over MCP (JSON-RPC on stdio)", setup: mcpCommand},
+	{name: "clean", args: "[directory]", summary: "Remove all map files in the workspace", setup: cleanCommand},
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		printUsage()
-		os.Exit(1)
-	}
+	os.Exit(runCLI(os.Args[1:]))
+}
 
-	command := os.Args[1]
+// scanResult is what scan prints with --json. Paths are relative to Root.
+type scanResult struct {
+	Root     string   `json:"root"`
+	Files    int      `json:"files"`
+	Mapped   []string `json:"mapped"`
+	Removed  []string `json:"removed"`
+	Errors   []string `json:"errors,omitempty"` // files that failed to map
+	DryRun   bool     `json:"dry_run,omitempty"`
+	Duration string   `json:"duration"`
+}
 
-	switch command {
-	case "scan":
-		targetDir := "."
-		if len(os.Args) >= 3 {
-			targetDir = os.Args[2]
+func scanCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	format := flags.String("format", "", "map output format: text, json or both (default from config, else text)")
+	force := flags.Bool("force", false, "ignore the manifest and remap every file")
+	out := flags.String("out", "", "write maps to this directory (e.g. .astrmap), mirroring the tree, instead of next to the sources")
+	dryRun := flags.Bool("dry-run", false, "report what would be remapped without writing anything")
+	workers := flags.Int("workers", 0, "number of files parsed at once (default from config, else GOMAXPROCS)")
+	detail := flags.Bool("detail", false, "add signatures and doc comments to maps (default from config)")
+	return func(args []string) error {
+		if err := maxArgs(args, 1); err != nil {
+			return err
+		}
+		if *format != "" && !config.ValidFormat(*format) {
+			return usageError(fmt.Sprintf("unknown format: %s (want text, json or both)", *format))
 		}
-		runScan(targetDir)
-	case "clean":
-		targetDir := "."
-		if len(os.Args) >= 3 {
-			targetDir = os.Args[2]
+		if *workers < 0 {
+			return usageError("--workers must not be negative")
 		}
-		runClean(targetDir)
-	default:
-		fmt.Printf("Unknown command: %s\n", command)
-		printUsage()
-		os.Exit(1)
+		return runScan(g, argOr(args, "."), scanOptions{
+			format: *format, out: *out, workers: *workers,
+			detail: *detail, force: *force, dryRun: *dryRun,
+		})
 	}
 }
 
-func printUsage() {
-	fmt.Println("🗺️  AstrMap - The AST Indexer for LLMs")
-	fmt.Println("\nUsage:")
-	fmt.Println("  astrmap scan [directory]   Scan directory and generate .map.txt files")
-	fmt.Println("  astrmap clean              Remove all .map.txt files in the current workspace")
+type scanOptions struct {
+	format, out           string
+	workers               int
+	detail, force, dryRun bool
 }
 
-func runScan(targetDir string) {
+func runScan(g *globals, targetDir string, opts scanOptions) error {
+	format, out, force, dryRun := opts.format, opts.out, opts.force, opts.dryRun
 	absTarget, err := filepath.Abs(targetDir)
 	if err != nil {
-		log.Fatalf("Invalid directory: %v", err)
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	if info, err := os.Stat(absTarget); err != nil || !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", targetDir)
 	}
 
-	fmt.Printf("🚀 Scanning %s...\n", absTarget)
+	cfg, err := g.loadConfig(absTarget)
+	if err != nil {
+		return err
+	}
+
+	g.infof("🚀 Scanning %s...\n", absTarget)
 	start := time.Now()
 
-	// Load or mock config
-	cfg := config.LoadOrSetup()
+	if format != "" {
+		cfg.Format = format
+	}
+	if out != "" {
+		cfg.OutDir = out
+	}
+	if err := mapper.CheckOutDir(cfg); err != nil {
+		return err
+	}
+	if opts.workers > 0 {
+		cfg.Workers = opts.workers
+	}
+	if opts.detail {
+		cfg.Detail = true
+	}
 
 	// 1. Find all allowed files
-	var allFiles []string
-	validExts := make(map[string]bool)
-	for _, root := range cfg.Roots {
-		for _, ext := range root.AllowedExts {
-			validExts[ext] = true
-		}
+	allFiles := mapper.CollectFiles(cfg)
+
+	g.infof("Found %d files to map.\n", len(allFiles))
+
+	// 2. Map changed files only (see the manifest at the scan root)
+	var res mapper.SyncResult
+	var deps *graph.Graph
+	if dryRun {
+		res = mapper.Pending(cfg, absTarget, allFiles, force)
+	} else {
+		res = mapper.Sync(cfg, absTarget, allFiles, force)
+		// 3. Generate Level Maps for the affected folders
+		mapper.RefreshFolderMaps(cfg, absTarget, allFiles, res.DirtyDirs)
+		// 4. Resolve imports into the dependency map
+		deps = saveGraph(cfg, absTarget, allFiles)
 	}
 
-	filepath.Walk(absTarget, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+	result := scanResult{
+		Root:     absTarget,
+		Files:    len(allFiles),
+		Mapped:   relPaths(absTarget, res.Mapped),
+		Removed:  relPaths(absTarget, res.Removed),
+		Errors:   errorStrings(res.Errors),
+		DryRun:   dryRun,
+		Duration: time.Since(start).String(),
+	}
+	err = g.emit(result, func() {
+		for _, e := range res.Errors {
+			log.Printf("❌ %v", e)
 		}
-
-		if info.IsDir() {
-			// Check against git / node_modules default ignores if not in config
-			if info.Name() == ".git" || info.Name() == "node_modules" {
-				return filepath.SkipDir
+		if dryRun {
+			for _, f := range result.Mapped {
+				fmt.Printf("would map    %s\n", f)
 			}
-			for _, root := range cfg.Roots {
-				if fs.ShouldIgnore(path, info, root.IgnoredDirs) {
-					return filepath.SkipDir
-				}
+			for _, f := range result.Removed {
+				fmt.Printf("would remove %s\n", f)
 			}
-			return nil
+			g.infof("%d files would be mapped (%d unchanged, %d removed).\n",
+				len(res.Mapped), len(allFiles)-len(res.Mapped), len(res.Removed))
+			return
 		}
-
-		ext := filepath.Ext(path)
-		if validExts[ext] {
-			allFiles = append(allFiles, path)
+		g.infof("Mapped %d changed files (%d unchanged, %d removed).\n",
+			len(res.Mapped), len(allFiles)-len(res.Mapped)-len(res.Errors), len(res.Removed))
+		if deps != nil {
+			g.infof("🔗 Resolved %d dependencies (%d imports outside the workspace or missing).\n", len(deps.Edges), len(deps.Unresolved))
+		}
+		if len(res.Errors) == 0 {
+			g.infof("✅ Mapping complete in %v. Check the _level_*.map.txt files!\n", time.Since(start))
 		}
-		return nil
 	})
+	if err == nil && len(res.Errors) > 0 {
+		err = fmt.Errorf("%d of %d files failed to map", len(res.Errors), len(allFiles))
+	}
+	return err
+}
 
-	fmt.Printf("Found %d files to map.\n", len(allFiles))
+func cleanCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	out := flags.String("out", "", "output directory to remove as well (default from config)")
+	dryRun := flags.Bool("dry-run", false, "list the files that would be removed without removing them")
+	return func(args []string) error {
+		if err := maxArgs(args, 1); err != nil {
+			return err
+		}
+		return runClean(g, argOr(args, "."), *out, *dryRun)
+	}
+}
 
-	// 2. Map individual files
-	for _, f := range allFiles {
-		mapper.GenerateMap(f)
+func runClean(g *globals, targetDir, out string, dryRun bool) error {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	if info, err := os.Stat(absTarget); err != nil || !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", targetDir)
+	}
+
+	cfg, err := g.loadConfig(absTarget)
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		cfg.OutDir = out
+	}
+	if err := mapper.CheckOutDir(cfg); err != nil {
+		return err
 	}
 
-	// 3. Generate Level Maps
-	mapper.GenerateFolderMaps(cfg, allFiles)
+	removed := mapper.DeepClean(cfg, absTarget, dryRun)
+	result := struct {
+		Removed []string `json:"removed"`
+		DryRun  bool     `json:"dry_run,omitempty"`
+	}{relPaths(absTarget, removed), dryRun}
+	return g.emit(result, func() {
+		if dryRun {
+			for _, f := range result.Removed {
+				fmt.Printf("would remove %s\n", f)
+			}
+			return
+		}
+		g.infof("✅ Workspace cleaned.\n")
+	})
+}
 
-	fmt.Printf("✅ Mapping complete in %v. Check the _level_*.map.txt files!\n", time.Since(start))
+// errorStrings returns the messages of errs.
+func errorStrings(errs []error) []string {
+	var list []string
+	for _, err := range errs {
+		list = append(list, err.Error())
+	}
+	return list
 }
 
-func runClean(targetDir string) {
-	absTarget, err := filepath.Abs(targetDir)
-	if err != nil {
-		log.Fatalf("Invalid directory: %v", err)
+// relPaths makes paths relative to root, with forward slashes.
+func relPaths(root string, paths []string) []string {
+	rel := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if r, err := filepath.Rel(root, p); err == nil {
+			p = r
+		}
+		rel = append(rel, filepath.ToSlash(p))
+	}
+	return rel
+}
+
+// saveGraph writes the dependency map of root for files. Files that fail to
+// map are reported by the scan itself.
+func saveGraph(cfg config.Config, root string, files []string) *graph.Graph {
+	deps, _ := graph.Build(cfg, root, files)
+	if err := graph.Save(cfg, deps); err != nil {
+		log.Printf("❌ %v", err)
 	}
-	mapper.DeepClean(absTarget)
-	fmt.Println("✅ Workspace cleaned.")
+	return deps
 }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hubby247/astrmap/pkg/mapper"
+	"github.com/hubby247/astrmap/pkg/mcp"
+)
+
+func mcpCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	out := flags.String("out", "", "output directory for maps (default from config, else next to the sources)")
+	return func(args []string) error {
+		if err := maxArgs(args, 1); err != nil {
+			return err
+		}
+		return runMCP(g, argOr(args, "."), *out)
+	}
+}
+
+// runMCP serves the maps of targetDir over MCP on stdin/stdout. Stdout
+// carries protocol messages only; logs go to stderr.
+func runMCP(g *globals, targetDir, out string) error {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	cfg, err := g.loadConfig(absTarget)
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		cfg.OutDir = out
+	}
+	if err := mapper.CheckOutDir(cfg); err != nil {
+		return err
+	}
+
+	log.Printf("🤖 Serving maps of %s over MCP (stdio)...", absTarget)
+	if err := mcp.NewServer(cfg, absTarget).Serve(os.Stdin, os.Stdout); err != nil {
+		return fmt.Errorf("MCP server stopped: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/hubby247/astrmap/pkg/pack"
+)
+
+func packCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	budget := flags.Int("budget", 8000, "maximum estimated tokens of the document")
+	focus := flags.String("focus", "", "file, folder or symbol to keep in most detail")
+	return func(args []string) error {
+		if err := maxArgs(args, 1); err != nil {
+			return err
+		}
+		if *budget <= 0 {
+			return usageError("--budget must be positive")
+		}
+		return runPack(g, argOr(args, "."), pack.Options{Budget: *budget, Focus: *focus})
+	}
+}
+
+// packResult is what pack prints with --json.
+type packResult struct {
+	Root string `json:"root"`
+	pack.Result
+}
+
+// runPack prints one map of targetDir that fits the token budget.
+func runPack(g *globals, targetDir string, opts pack.Options) error {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	cfg, err := g.loadConfig(absTarget)
+	if err != nil {
+		return err
+	}
+
+	res, err := pack.Build(cfg, absTarget, opts)
+	if errors.Is(err, pack.ErrNoFocus) {
+		log.Printf("🔍 %v", err)
+		return errNoMatch
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("📦 Packed %d of %d files (%d with regions) in ~%d of %d tokens", res.Shown, res.Files, res.Detailed, res.Tokens, res.Budget)
+	return g.emit(packResult{Root: absTarget, Result: res}, func() {
+		fmt.Print(res.Text)
+	})
+}
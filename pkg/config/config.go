@@ -2,15 +2,25 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-
-	"github.com/hubby247/astrmap/pkg/fs"
+	"runtime"
 )
 
 const (
 	ConfigFile = "codemap.json"
+	// DefaultOutDir is the suggested output directory for out-of-tree maps
+	DefaultOutDir = ".astrmap"
+)
+
+// Map output formats
+const (
+	FormatText = "text" // .map.txt files only (default)
+	FormatJSON = "json" // .map.json files and per-folder _index.map.json
+	FormatBoth = "both" // both of the above
 )
 
 // RootConfig defines a directory to map
@@ -23,6 +33,21 @@ type RootConfig struct {
 // Config holds the application configuration
 type Config struct {
 	Roots []RootConfig `json:"roots"`
+	// Format selects the map output: "text" (default), "json" or "both"
+	Format string `json:"format,omitempty"`
+	// OutDir, when set, receives every map in a mirror of the source tree
+	// instead of writing maps next to the sources. A relative path is
+	// resolved against each root.
+	OutDir string `json:"out_dir,omitempty"`
+	// Detail adds the signature and the first sentence of the doc comment
+	// of every declaration to maps
+	Detail bool `json:"detail,omitempty"`
+	// Workers bounds how many files are parsed at once (default GOMAXPROCS)
+	Workers int `json:"workers,omitempty"`
+	// File is the config file the config was loaded from, if any
+	File string `json:"-"`
+	// Scope, when set, limits every walk to this directory (see WalkRoots)
+	Scope string `json:"-"`
 	// Transient Command Field (for IPC via file)
 	Command *CommandPayload `json:"_command,omitempty"`
 }
@@ -34,96 +59,122 @@ type CommandPayload struct {
 	Timestamp int64  `json:"timestamp"`
 }
 
-// LoadOrSetup tries to load config or starts interactive setup
-func LoadOrSetup() Config {
-	// Try load
-	if _, err := os.Stat(ConfigFile); err == nil {
-		data, err := os.ReadFile(ConfigFile)
-		if err == nil {
-			var cfg Config
-			// Try V2 format
-			if err := json.Unmarshal(data, &cfg); err == nil {
-				if len(cfg.Roots) > 0 {
-					log.Printf("✅ Loaded config with %d roots.", len(cfg.Roots))
-					return cfg
-				}
-			}
-
-			// Fallback: Try V1 format and migrate
-			var v1 struct {
-				AllowedExts []string `json:"allowed_exts"`
-				RootPath    string   `json:"root_path"`
-			}
-			if err := json.Unmarshal(data, &v1); err == nil {
-				log.Println("⚠️ Detected V1 config. Migrating to V2...")
-				if v1.RootPath == "" || v1.RootPath == "." {
-					abs, _ := filepath.Abs(".")
-					v1.RootPath = abs
-				}
-				newCfg := Config{
-					Roots: []RootConfig{
-						{Path: v1.RootPath, AllowedExts: v1.AllowedExts},
-					},
-				}
-				Save(newCfg)
-				return newCfg
-			}
+// Find looks for ConfigFile in dir and then in its parents, the way git
+// finds .git. Without one it returns an error matching os.ErrNotExist.
+func Find(dir string) (string, error) {
+	start, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for dir = start; ; {
+		path := filepath.Join(dir, ConfigFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %s in %s or its parents: %w", ConfigFile, start, os.ErrNotExist)
 		}
+		dir = parent
 	}
+}
 
-	// Interactive Setup
-	log.Println("🔍 No valid config found. Starting interactive setup...")
-	return initInteractiveSetup()
+// LoadFile reads the config at path without ever creating one. Relative root
+// paths are resolved against the directory of the file, so the result does
+// not depend on the working directory. A V1 config is migrated in memory only.
+func LoadFile(path string) (Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Config{}, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg, migrated, err := parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.File = abs
+	for i, rc := range cfg.Roots {
+		if !filepath.IsAbs(rc.Path) {
+			rc.Path = filepath.Join(filepath.Dir(abs), rc.Path)
+		}
+		cfg.Roots[i].Path = filepath.Clean(rc.Path)
+	}
+	if migrated {
+		log.Println("⚠️ Detected V1 config. Using it as V2; run 'astrmap init --force' to rewrite it.")
+	} else {
+		log.Printf("✅ Loaded %s with %d roots.", abs, len(cfg.Roots))
+	}
+	return cfg, nil
 }
 
-func Save(cfg Config) {
-	data, _ := json.MarshalIndent(cfg, "", "  ")
-	os.WriteFile(ConfigFile, data, 0644)
+// parse decodes a V2 config, or a V1 config migrated to V2.
+func parse(data []byte) (cfg Config, migrated bool, err error) {
+	// Try V2 format
+	if err := json.Unmarshal(data, &cfg); err == nil && len(cfg.Roots) > 0 {
+		return cfg, false, nil
+	}
+
+	// Fallback: Try V1 format and migrate
+	var v1 struct {
+		AllowedExts []string `json:"allowed_exts"`
+		RootPath    string   `json:"root_path"`
+	}
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return Config{}, false, err
+	}
+	if v1.AllowedExts == nil && v1.RootPath == "" {
+		return Config{}, false, errors.New("no roots configured")
+	}
+	if v1.RootPath == "" {
+		v1.RootPath = "."
+	}
+	return Config{
+		Roots: []RootConfig{
+			{Path: v1.RootPath, AllowedExts: v1.AllowedExts},
+		},
+	}, true, nil
 }
 
-func initInteractiveSetup() Config {
-	// Default Root is CWD
-	cwd, _ := os.Getwd()
-	absRoot, _ := filepath.Abs(cwd)
+// MapFormat returns the configured output format, defaulting to text.
+func (c Config) MapFormat() string {
+	if c.Format == "" {
+		return FormatText
+	}
+	return c.Format
+}
 
-	log.Printf("🔍 Scanning directory '%s' for file types...", absRoot)
+// WritesText reports whether pipe-delimited .map.txt files should be written.
+func (c Config) WritesText() bool {
+	return c.MapFormat() == FormatText || c.Format == FormatBoth
+}
 
-	extCounts := make(map[string]int)
-	filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil || fs.ShouldIgnore(path, info, nil) {
-			if info != nil && info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		if !info.IsDir() {
-			ext := filepath.Ext(path)
-			if ext != "" {
-				extCounts[ext]++
-			}
-		}
-		return nil
-	})
+// WritesJSON reports whether structured .map.json files should be written.
+func (c Config) WritesJSON() bool {
+	return c.Format == FormatJSON || c.Format == FormatBoth
+}
 
-	var suggestedExts []string
-	for ext, count := range extCounts {
-		if count > 0 {
-			suggestedExts = append(suggestedExts, ext)
-		}
+// WorkerCount returns the configured number of workers, defaulting to
+// GOMAXPROCS.
+func (c Config) WorkerCount() int {
+	if c.Workers > 0 {
+		return c.Workers
 	}
+	return runtime.GOMAXPROCS(0)
+}
 
-	log.Println("Found extensions:", suggestedExts)
-	log.Println("Auto-configuring to watch these extensions.")
+// ValidFormat reports whether f is a known map output format.
+func ValidFormat(f string) bool {
+	return f == FormatText || f == FormatJSON || f == FormatBoth
+}
 
-	cfg := Config{
-		Roots: []RootConfig{
-			{
-				Path:        absRoot,
-				AllowedExts: suggestedExts,
-				IgnoredDirs: []string{"node_modules", ".git", "dist", "build"},
-			},
-		},
+// Save writes cfg to path.
+func Save(path string, cfg Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
 	}
-	Save(cfg)
-	return cfg
+	return os.WriteFile(path, append(data, '\n'), 0644)
 }
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	base := t.TempDir()
+	for _, dir := range []string{"proj/a/b", "proj/dir/codemap.json", "proj/nested/c", "other"} {
+		if err := os.MkdirAll(filepath.Join(base, filepath.FromSlash(dir)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"proj/codemap.json", "proj/nested/codemap.json"} {
+		if err := os.WriteFile(filepath.Join(base, filepath.FromSlash(file)), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dir, want string // slash paths relative to base; want "" for none
+	}{
+		{"proj", "proj/codemap.json"},
+		{"proj/a/b", "proj/codemap.json"},
+		{"proj/dir", "proj/codemap.json"}, // a directory named codemap.json is no config
+		{"proj/nested/c", "proj/nested/codemap.json"},
+		{"other", ""},
+	}
+	for _, tt := range tests {
+		got, err := Find(filepath.Join(base, filepath.FromSlash(tt.dir)))
+		if tt.want == "" {
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Find(%s) = %q, %v, want an os.ErrNotExist error", tt.dir, got, err)
+			}
+			continue
+		}
+		if want := filepath.Join(base, filepath.FromSlash(tt.want)); err != nil || got != want {
+			t.Errorf("Find(%s) = %q, %v, want %q", tt.dir, got, err, want)
+		}
+	}
+}
+
+func TestLoadFileResolvesRootsAgainstTheFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFile)
+	if err := os.WriteFile(path, []byte(`{"roots": [{"path": "src", "allowed_exts": [".go"]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Roots) != 1 || cfg.Roots[0].Path != filepath.Join(dir, "src") {
+		t.Errorf("roots = %+v, want %s", cfg.Roots, filepath.Join(dir, "src"))
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/fs"
+)
+
+// DefaultIgnoredDirs are ignored by every root unless configured otherwise.
+var DefaultIgnoredDirs = []string{"node_modules", ".git", "dist", "build"}
+
+// commonIgnores are folder names proposed as ignores when a tree has them:
+// build output, dependencies, caches and virtual environments.
+var commonIgnores = []string{
+	"build", "dist", "out", "target", "bin", "obj", "coverage",
+	"node_modules", "vendor", "bower_components", "__pycache__",
+	"venv",
+}
+
+// Detection is what Detect found in a tree.
+type Detection struct {
+	Root    string         `json:"root"`
+	Counts  map[string]int `json:"counts"`  // files per supported extension
+	Exts    []string       `json:"exts"`    // supported extensions found, most files first
+	Ignores []string       `json:"ignores"` // proposed ignored folder names
+}
+
+// Detect walks root and counts the files of each supported extension, so
+// only languages that can actually be parsed end up in a config. Folders that
+// look like build output or dependencies are proposed as ignores instead of
+// being walked.
+func Detect(root string, supported []string) Detection {
+	d := Detection{Root: root, Counts: make(map[string]int)}
+	isSupported := make(map[string]bool, len(supported))
+	for _, ext := range supported {
+		isSupported[strings.ToLower(ext)] = true
+	}
+	isCommon := make(map[string]bool, len(commonIgnores))
+	for _, name := range commonIgnores {
+		isCommon[name] = true
+	}
+	proposed := make(map[string]bool)
+
+	ignorer := fs.NewIgnorer(root)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == root {
+			return nil
+		}
+		if info.IsDir() {
+			if isCommon[info.Name()] {
+				proposed[info.Name()] = true
+				return filepath.SkipDir
+			}
+			if fs.ShouldIgnore(path, info, nil) || ignorer.Match(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if isSupported[ext] && !ignorer.Match(path, false) {
+			d.Counts[ext]++
+		}
+		return nil
+	})
+
+	for ext := range d.Counts {
+		d.Exts = append(d.Exts, ext)
+	}
+	sort.Slice(d.Exts, func(i, j int) bool {
+		if d.Counts[d.Exts[i]] != d.Counts[d.Exts[j]] {
+			return d.Counts[d.Exts[i]] > d.Counts[d.Exts[j]]
+		}
+		return d.Exts[i] < d.Exts[j]
+	})
+
+	d.Ignores = append(d.Ignores, DefaultIgnoredDirs...)
+	for _, name := range commonIgnores {
+		if proposed[name] && !contains(d.Ignores, name) {
+			d.Ignores = append(d.Ignores, name)
+		}
+	}
+	return d
+}
+
+// Default returns the config used when none exists: one root at root,
+// mapping every supported extension, with the default ignores.
+func Default(root string, supported []string) Config {
+	return Config{
+		Roots: []RootConfig{
+			{Path: root, AllowedExts: supported, IgnoredDirs: DefaultIgnoredDirs},
+		},
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// RootOf returns the innermost root containing path. Root paths are
+// expected to be absolute, as LoadFile and Default make them.
+func (c Config) RootOf(path string) (RootConfig, bool) {
+	var best RootConfig
+	found := false
+	for _, rc := range c.Roots {
+		if Within(path, rc.Path) && (!found || len(rc.Path) > len(best.Path)) {
+			best, found = rc, true
+		}
+	}
+	return best, found
+}
+
+// WalkRoots returns the directories to walk when mapping, with the settings
+// of their roots. Without a Scope these are the roots themselves. With one,
+// they are the Scope itself when a root contains it, or else every root
+// inside the Scope; nil means the Scope is outside every root.
+func (c Config) WalkRoots() []RootConfig {
+	if c.Scope == "" {
+		return c.Roots
+	}
+	if rc, ok := c.RootOf(c.Scope); ok {
+		rc.Path = c.Scope
+		return []RootConfig{rc}
+	}
+	var roots []RootConfig
+	for _, rc := range c.Roots {
+		if Within(rc.Path, c.Scope) {
+			roots = append(roots, rc)
+		}
+	}
+	return roots
+}
+
+// Within reports whether path is dir or below it. Like the rest of the
+// mapper, it ignores case.
+func Within(path, dir string) bool {
+	rel, err := filepath.Rel(strings.ToLower(filepath.Clean(dir)), strings.ToLower(filepath.Clean(path)))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
 )
 
 var (
@@ -59,3 +61,112 @@ func FormatSize(bytes int64) string {
 	}
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
+
+// SplitList splits a comma-separated flag or argument into its trimmed
+// items, dropping empty ones. An empty string gives nil.
+func SplitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// MatchGlob reports whether the slash-separated path matches pattern.
+// "*" and "?" never cross a "/", "**" matches any number of directories and
+// "[...]" classes work as in path.Match.
+func MatchGlob(pattern, path string) bool {
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
+
+var (
+	globMu    sync.Mutex
+	globCache = make(map[string]*regexp.Regexp)
+)
+
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	globMu.Lock()
+	defer globMu.Unlock()
+	if re, ok := globCache[pattern]; ok {
+		return re, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// "**/" matches zero or more leading directories
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, err
+	}
+	globCache[pattern] = re
+	return re, nil
+}
+
+// ReadLines returns lines from..to (1-based, inclusive) of the file at path,
+// clipped to the file. The first returned line is number max(from, 1).
+func ReadLines(path string, from, to int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if from < 1 {
+		from = 1
+	}
+	var lines []string
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; n <= to && s.Scan(); n++ {
+		if n >= from {
+			lines = append(lines, s.Text())
+		}
+	}
+	return lines, s.Err()
+}
@@ -0,0 +1,197 @@
+package fs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IgnoreFiles are read in every directory, in this order. Rules of later
+// files, and of deeper directories, win over earlier ones.
+var IgnoreFiles = []string{".gitignore", ".astrmapignore"}
+
+type ignoreRule struct {
+	pattern string // glob matched against the slash path relative to the rule's directory
+	negate  bool   // "!pattern" re-includes what earlier rules excluded
+	dirOnly bool   // "pattern/" only matches directories
+}
+
+// Ignorer applies gitignore-style rules to the tree below a root: nested
+// .gitignore and .astrmapignore files plus .git/info/exclude, with negation,
+// anchored patterns and "**" globs. Ignore files are read lazily and cached
+// until Reset.
+type Ignorer struct {
+	root string
+
+	mu    sync.Mutex
+	rules map[string][]ignoreRule // slash dir relative to root -> rules
+}
+
+// NewIgnorer returns an Ignorer for the tree at root.
+func NewIgnorer(root string) *Ignorer {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = root
+	}
+	return &Ignorer{root: abs, rules: make(map[string][]ignoreRule)}
+}
+
+// Reset forgets the cached ignore files, e.g. after one of them changed.
+func (ig *Ignorer) Reset() {
+	ig.mu.Lock()
+	defer ig.mu.Unlock()
+	ig.rules = make(map[string][]ignoreRule)
+}
+
+// Match reports whether the rules exclude path itself. It does not look at
+// the parent directories: walks that skip excluded directories never reach
+// their contents anyway. Use Ignored for a path on its own.
+func (ig *Ignorer) Match(p string, isDir bool) bool {
+	rel, ok := ig.rel(p)
+	if !ok || rel == "." {
+		return false
+	}
+	return ig.match(rel, isDir)
+}
+
+// Ignored reports whether path or one of its parent directories below the
+// root is excluded. As in git, files in an excluded directory cannot be
+// re-included.
+func (ig *Ignorer) Ignored(p string, isDir bool) bool {
+	rel, ok := ig.rel(p)
+	if !ok || rel == "." {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		last := i == len(parts)-1
+		if ig.match(strings.Join(parts[:i+1], "/"), isDir || !last) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ig *Ignorer) rel(p string) (string, bool) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(ig.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// match applies the rules of every directory from the root down to the
+// parent of rel; the last matching rule decides.
+func (ig *Ignorer) match(rel string, isDir bool) bool {
+	ignored := false
+	dir := ""
+	for {
+		sub := rel
+		if dir != "" {
+			sub = strings.TrimPrefix(rel, dir+"/")
+		}
+		for _, r := range ig.load(dir) {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if MatchGlob(r.pattern, sub) {
+				ignored = !r.negate
+			}
+		}
+
+		// Descend one directory towards rel
+		next, _, found := strings.Cut(sub, "/")
+		if !found {
+			return ignored
+		}
+		if dir == "" {
+			dir = next
+		} else {
+			dir += "/" + next
+		}
+	}
+}
+
+// load returns the rules of the ignore files in dir (slash, relative to root).
+func (ig *Ignorer) load(dir string) []ignoreRule {
+	ig.mu.Lock()
+	defer ig.mu.Unlock()
+	if rules, ok := ig.rules[dir]; ok {
+		return rules
+	}
+
+	abs := filepath.Join(ig.root, filepath.FromSlash(dir))
+	var files []string
+	if dir == "" {
+		files = append(files, filepath.Join(abs, ".git", "info", "exclude"))
+	}
+	for _, name := range IgnoreFiles {
+		files = append(files, filepath.Join(abs, name))
+	}
+
+	var rules []ignoreRule
+	for _, f := range files {
+		rules = append(rules, readIgnoreFile(f)...)
+	}
+	ig.rules[dir] = rules
+	return rules
+}
+
+func readIgnoreFile(name string) []ignoreRule {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if r, ok := parseIgnoreLine(s.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// parseIgnoreLine turns one gitignore line into a rule. Blank lines and
+// comments yield ok == false.
+func parseIgnoreLine(line string) (r ignoreRule, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return r, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the
+	// directory of the ignore file; otherwise it matches at any depth.
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+	r.pattern = line
+	return r, true
+}
@@ -0,0 +1,84 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   ignoreRule
+		wantOK bool
+	}{
+		{"", ignoreRule{}, false},
+		{"# comment", ignoreRule{}, false},
+		{"/", ignoreRule{}, false},
+		{"*.log", ignoreRule{pattern: "**/*.log"}, true},
+		{"*.log   ", ignoreRule{pattern: "**/*.log"}, true},
+		{"*.log\r", ignoreRule{pattern: "**/*.log"}, true},
+		{"build/", ignoreRule{pattern: "**/build", dirOnly: true}, true},
+		{"/dist", ignoreRule{pattern: "dist"}, true},
+		{"docs/*.md", ignoreRule{pattern: "docs/*.md"}, true},
+		{"!keep.log", ignoreRule{pattern: "**/keep.log", negate: true}, true},
+		{`\!bang`, ignoreRule{pattern: "**/!bang"}, true},
+		{`\#hash`, ignoreRule{pattern: "**/#hash"}, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseIgnoreLine(tt.line)
+		if ok != tt.wantOK || ok && got != tt.want {
+			t.Errorf("parseIgnoreLine(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestIgnorer(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":            "*.log\n!keep.log\nbuild/\n/dist\n",
+		".astrmapignore":        "secret.go\n",
+		"sub/.gitignore":        "local.txt\n!secret.go\n",
+		".git/info/exclude":     "scratch/\n",
+		"docs/.astrmapignore":   "**/draft*\n",
+		"docs/guide/.gitignore": "",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"main.go", false, false},
+		{"app.log", false, true},
+		{"sub/deep/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false}, // a file named like an ignored directory
+		{"build/out.go", false, true},
+		{"dist", true, true},
+		{"sub/dist", true, false}, // anchored to the root
+		{"secret.go", false, true},
+		{"sub/secret.go", false, false}, // re-included by the deeper rule
+		{"local.txt", false, false},
+		{"sub/local.txt", false, true},
+		{"scratch/a.go", false, true},
+		{"docs/guide/draft-1.md", false, true},
+		{"draft.md", false, false},
+		{"../outside.log", false, false},
+	}
+	ig := NewIgnorer(root)
+	for _, tt := range tests {
+		if got := ig.Ignored(filepath.Join(root, filepath.FromSlash(tt.path)), tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
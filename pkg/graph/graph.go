@@ -0,0 +1,183 @@
+// Package graph resolves the imports recorded in file maps to the files of
+// the workspace they point at, and saves the result as a dependency map.
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+// Edge is an import of one workspace file by another. Paths are slash
+// paths relative to the graph root.
+type Edge struct {
+	From   string `json:"from"`
+	To     string `json:"to,omitempty"` // empty when Import is unresolved
+	Import string `json:"import"`       // as written in From
+	Line   int    `json:"line"`
+	// Missing marks an unresolved import of a workspace path, such as a
+	// relative import of a file that does not exist
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Graph is the dependency map of a workspace.
+type Graph struct {
+	Root  string   `json:"root"`
+	Files []string `json:"files"`
+	Edges []Edge   `json:"edges"`
+	// Unresolved are imports of packages outside the workspace (standard
+	// library, node_modules, site-packages...) and of missing files
+	Unresolved []Edge `json:"unresolved,omitempty"`
+}
+
+// Build resolves the imports of files (absolute paths below root) into a
+// graph. Maps are read back when current and reparsed otherwise; files
+// whose map cannot be read are left out and returned as errors.
+func Build(cfg config.Config, root string, files []string) (*Graph, []error) {
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+	maps, errs := mapper.CurrentFileMaps(cfg, files)
+
+	g := &Graph{Root: root, Files: []string{}, Edges: []Edge{}}
+	var failed []error
+	var mapped []string
+	for i, f := range files {
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+			continue
+		}
+		mapped = append(mapped, f)
+		g.Files = append(g.Files, rel(root, f))
+	}
+
+	r := newResolver(root, mapped)
+	for i, f := range files {
+		if errs[i] != nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, region := range maps[i].Regions {
+			if region.Kind != mapper.KindImport || region.Name == "" {
+				continue
+			}
+			targets, local := r.resolve(f, region.Name)
+			if len(targets) == 0 {
+				g.Unresolved = append(g.Unresolved, Edge{From: rel(root, f), Import: region.Name, Line: region.Start, Missing: local})
+				continue
+			}
+			for _, t := range targets {
+				if t == f || seen[t] {
+					continue
+				}
+				seen[t] = true
+				g.Edges = append(g.Edges, Edge{From: rel(root, f), To: rel(root, t), Import: region.Name, Line: region.Start})
+			}
+		}
+	}
+	return g, failed
+}
+
+func rel(root, path string) string {
+	if r, err := filepath.Rel(root, path); err == nil {
+		return filepath.ToSlash(r)
+	}
+	return filepath.ToSlash(path)
+}
+
+// Text renders the graph as a text map: every file with dependencies,
+// followed by the files it imports ("→") and its missing imports ("?").
+// An import of a whole package, like a Go import, is one "→ dir/" line.
+// Imports outside the workspace are only counted.
+func (g *Graph) Text() string {
+	var sb strings.Builder
+	missing := 0
+	for _, e := range g.Unresolved {
+		if e.Missing {
+			missing++
+		}
+	}
+	fmt.Fprintf(&sb, "Dependencies: %s\n", g.Root)
+	fmt.Fprintf(&sb, "Files: %d, Edges: %d, External: %d, Missing: %d\n", len(g.Files), len(g.Edges), len(g.Unresolved)-missing, missing)
+	sb.WriteString("--------------------------------------------------\n")
+
+	edges := make(map[string][]Edge)
+	for _, e := range g.Edges {
+		edges[e.From] = append(edges[e.From], e)
+	}
+	for _, e := range g.Unresolved {
+		if e.Missing {
+			edges[e.From] = append(edges[e.From], e)
+		}
+	}
+	for _, f := range g.Files {
+		if len(edges[f]) == 0 {
+			continue
+		}
+		sb.WriteString(f + "\n")
+		targets := make(map[string]int) // files per import
+		for _, e := range edges[f] {
+			targets[e.Import]++
+		}
+		for _, e := range edges[f] {
+			switch {
+			case e.To == "":
+				fmt.Fprintf(&sb, "  ? %s\n", e.Import)
+			case targets[e.Import] > 1:
+				fmt.Fprintf(&sb, "  → %s/ (%d files)\n", path.Dir(e.To), targets[e.Import])
+				targets[e.Import] = 0
+			case targets[e.Import] == 1:
+				fmt.Fprintf(&sb, "  → %s\n", e.To)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Path returns where the dependency map of root is saved in the given
+// suffix (mapper.TextMapSuffix or mapper.JSONMapSuffix).
+func Path(cfg config.Config, root, suffix string) string {
+	return mapper.FolderMapPath(cfg, root, mapper.DepsFile+suffix)
+}
+
+// Save writes the dependency map of the graph root in the formats cfg
+// writes, and removes the one of a format no longer written. Unchanged
+// files are not rewritten.
+func Save(cfg config.Config, g *Graph) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	outputs := []struct {
+		suffix string
+		write  bool
+		data   []byte
+	}{
+		{mapper.TextMapSuffix, cfg.WritesText(), []byte(g.Text())},
+		{mapper.JSONMapSuffix, cfg.WritesJSON(), append(data, '\n')},
+	}
+	for _, out := range outputs {
+		path := Path(cfg, g.Root, out.suffix)
+		if !out.write {
+			os.Remove(path)
+			continue
+		}
+		if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, out.data) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		if err := os.WriteFile(path, out.data, 0644); err != nil {
+			return fmt.Errorf("failed to write dependency map %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,542 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+// resolver maps import names to workspace files, per language.
+type resolver struct {
+	root  string
+	files map[string]bool     // absolute paths of the workspace files
+	dirs  map[string][]string // files by directory, for Go packages
+
+	goMods    map[string]*goModule // nearest go.mod by directory
+	tsConfigs map[string]*tsConfig // nearest tsconfig.json by directory
+}
+
+func newResolver(root string, files []string) *resolver {
+	r := &resolver{
+		root:      root,
+		files:     make(map[string]bool, len(files)),
+		dirs:      make(map[string][]string),
+		goMods:    make(map[string]*goModule),
+		tsConfigs: make(map[string]*tsConfig),
+	}
+	for _, f := range files {
+		r.files[f] = true
+		r.dirs[filepath.Dir(f)] = append(r.dirs[filepath.Dir(f)], f)
+	}
+	return r
+}
+
+// resolve returns the workspace files that the import spec of the file at
+// from points at, or nil when it is outside the workspace or missing. local
+// reports whether spec names a workspace path at all (a relative import, a
+// package of the module...), so that nil means a missing file.
+func (r *resolver) resolve(from, spec string) (files []string, local bool) {
+	switch mapper.Language(from) {
+	case "go":
+		return r.resolveGo(from, spec)
+	case "javascript", "typescript":
+		return r.resolveJS(from, spec)
+	case "python":
+		return r.resolvePython(from, spec)
+	case "rust":
+		return r.resolveRust(from, spec)
+	case "vue", "svelte", "astro":
+		// Script imports resolve like JavaScript ones; root-relative
+		// <script src> and <link href> ones like assets
+		if strings.HasPrefix(spec, "/") {
+			return r.resolveAsset(from, spec)
+		}
+		return r.resolveJS(from, spec)
+	case "html", "xml", "php", "css":
+		return r.resolveAsset(from, spec)
+	case "c", "cpp":
+		return r.resolveInclude(from, spec)
+	}
+	return nil, false
+}
+
+// existing returns the first candidate that is a workspace file.
+func (r *resolver) existing(candidates ...string) []string {
+	for _, c := range candidates {
+		if c = filepath.Clean(c); r.files[c] {
+			return []string{c}
+		}
+	}
+	return nil
+}
+
+// nearest walks up from dir to the first directory holding one of names
+// and returns its path, or "" when there is none.
+func nearest(dir string, names ...string) string {
+	for {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// 1. Go: import paths of the module (or of a local replacement) are
+// packages, which are all the non-test files of their directory
+
+// goModule is what a go.mod declares.
+type goModule struct {
+	dir      string
+	path     string
+	replaces map[string]string // module path -> local directory
+}
+
+func (r *resolver) goModule(dir string) *goModule {
+	if m, ok := r.goMods[dir]; ok {
+		return m
+	}
+	var m *goModule
+	if path := nearest(dir, "go.mod"); path != "" {
+		m = parseGoMod(path)
+	}
+	r.goMods[dir] = m
+	return m
+}
+
+// parseGoMod reads the module path and the local replace directives of a
+// go.mod file.
+func parseGoMod(path string) *goModule {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	m := &goModule{dir: filepath.Dir(path), replaces: make(map[string]string)}
+	inReplace := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case strings.HasPrefix(line, "module "):
+			m.path = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`)
+			continue
+		case line == "replace (":
+			inReplace = true
+			continue
+		case inReplace && line == ")":
+			inReplace = false
+			continue
+		case strings.HasPrefix(line, "replace "):
+			line = strings.TrimPrefix(line, "replace ")
+		case !inReplace:
+			continue
+		}
+		// old [version] => new [version]
+		old, target, ok := strings.Cut(line, "=>")
+		if !ok {
+			continue
+		}
+		oldFields, newFields := strings.Fields(old), strings.Fields(target)
+		if len(oldFields) == 0 || len(newFields) == 0 {
+			continue
+		}
+		if dir := newFields[0]; strings.HasPrefix(dir, "./") || strings.HasPrefix(dir, "../") || filepath.IsAbs(dir) {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(m.dir, dir)
+			}
+			m.replaces[oldFields[0]] = dir
+		}
+	}
+	return m
+}
+
+func (r *resolver) resolveGo(from, spec string) ([]string, bool) {
+	m := r.goModule(filepath.Dir(from))
+	if m == nil {
+		return nil, false
+	}
+	// The longest matching module wins
+	dir, matched := "", ""
+	if sub, ok := cutModule(spec, m.path); ok {
+		dir, matched = filepath.Join(m.dir, sub), m.path
+	}
+	for mod, local := range m.replaces {
+		if sub, ok := cutModule(spec, mod); ok && len(mod) > len(matched) {
+			dir, matched = filepath.Join(local, sub), mod
+		}
+	}
+	if dir == "" {
+		return nil, false
+	}
+	var pkg []string
+	for _, f := range r.dirs[filepath.Clean(dir)] {
+		if filepath.Ext(f) == ".go" && !strings.HasSuffix(f, "_test.go") {
+			pkg = append(pkg, f)
+		}
+	}
+	return pkg, true
+}
+
+// cutModule returns the directory of the package path within the module,
+// and whether the package belongs to the module at all.
+func cutModule(pkg, module string) (string, bool) {
+	if module == "" {
+		return "", false
+	}
+	if pkg == module {
+		return ".", true
+	}
+	sub, ok := strings.CutPrefix(pkg, module+"/")
+	return filepath.FromSlash(sub), ok
+}
+
+// 2. JavaScript / TypeScript: relative imports, and the "paths" and
+// "baseUrl" of the nearest tsconfig.json or jsconfig.json
+
+// jsExts are tried, in order, for imports without an extension.
+var jsExts = []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs", ".vue", ".svelte", ".astro"}
+
+// tsConfig is the module resolution part of a tsconfig.json.
+type tsConfig struct {
+	baseURL string              // absolute; "" when not set
+	paths   map[string][]string // pattern -> absolute targets, with "*"
+}
+
+func (r *resolver) tsConfig(dir string) *tsConfig {
+	if c, ok := r.tsConfigs[dir]; ok {
+		return c
+	}
+	var c *tsConfig
+	if path := nearest(dir, "tsconfig.json", "jsconfig.json"); path != "" {
+		c = parseTSConfig(path, 0)
+	}
+	r.tsConfigs[dir] = c
+	return c
+}
+
+// parseTSConfig reads the baseUrl and paths of a tsconfig.json, following
+// relative "extends" chains.
+func parseTSConfig(path string, depth int) *tsConfig {
+	data, err := os.ReadFile(path)
+	if err != nil || depth > 8 {
+		return nil
+	}
+	var raw struct {
+		Extends         string `json:"extends"`
+		CompilerOptions struct {
+			BaseURL *string             `json:"baseUrl"`
+			Paths   map[string][]string `json:"paths"`
+		} `json:"compilerOptions"`
+	}
+	if err := json.Unmarshal(stripJSONC(data), &raw); err != nil {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	c := &tsConfig{}
+	if ext := raw.Extends; strings.HasPrefix(ext, ".") {
+		if !strings.HasSuffix(ext, ".json") {
+			ext += ".json"
+		}
+		if base := parseTSConfig(filepath.Join(dir, ext), depth+1); base != nil {
+			*c = *base
+		}
+	}
+	if raw.CompilerOptions.BaseURL != nil {
+		c.baseURL = filepath.Join(dir, *raw.CompilerOptions.BaseURL)
+	}
+	if raw.CompilerOptions.Paths != nil {
+		// Paths are relative to baseUrl, or to the config that sets them
+		base := c.baseURL
+		if base == "" {
+			base = dir
+		}
+		c.paths = make(map[string][]string)
+		for pattern, targets := range raw.CompilerOptions.Paths {
+			for _, t := range targets {
+				c.paths[pattern] = append(c.paths[pattern], filepath.Join(base, t))
+			}
+		}
+	}
+	return c
+}
+
+// stripJSONC removes the comments and trailing commas tsconfig files allow.
+func stripJSONC(data []byte) []byte {
+	var out []byte
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out = append(out, '\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := strings.Index(string(data[i+2:]), "*/")
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == '}' || c == ']':
+			// Drop a comma before the closing bracket
+			j := len(out) - 1
+			for j >= 0 && strings.IndexByte(" \t\r\n", out[j]) >= 0 {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (r *resolver) resolveJS(from, spec string) ([]string, bool) {
+	if strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") || spec == "." || spec == ".." {
+		return r.jsFile(filepath.Join(filepath.Dir(from), spec)), true
+	}
+	c := r.tsConfig(filepath.Dir(from))
+	if c == nil {
+		return nil, false
+	}
+	// Like TypeScript, the pattern with the longest prefix wins
+	patterns := slices.Collect(maps.Keys(c.paths))
+	sort.Slice(patterns, func(i, j int) bool {
+		pi, _, _ := strings.Cut(patterns[i], "*")
+		pj, _, _ := strings.Cut(patterns[j], "*")
+		return len(pi) > len(pj) || len(pi) == len(pj) && patterns[i] < patterns[j]
+	})
+	local := false
+	for _, pattern := range patterns {
+		star, ok := matchPattern(pattern, spec)
+		if !ok {
+			continue
+		}
+		local = true
+		for _, t := range c.paths[pattern] {
+			if found := r.jsFile(strings.Replace(t, "*", star, 1)); found != nil {
+				return found, true
+			}
+		}
+	}
+	if c.baseURL != "" {
+		// A bare name may as well be a package of node_modules
+		if found := r.jsFile(filepath.Join(c.baseURL, spec)); found != nil {
+			return found, true
+		}
+	}
+	return nil, local
+}
+
+// matchPattern matches spec against a tsconfig paths pattern with at most
+// one "*", and returns what the "*" stands for.
+func matchPattern(pattern, spec string) (string, bool) {
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return "", pattern == spec
+	}
+	if len(spec) < len(prefix)+len(suffix) || !strings.HasPrefix(spec, prefix) || !strings.HasSuffix(spec, suffix) {
+		return "", false
+	}
+	return spec[len(prefix) : len(spec)-len(suffix)], true
+}
+
+// jsFile finds the module at base: the file itself, with an added
+// extension, its TypeScript source when imported as ".js", or an index file.
+func (r *resolver) jsFile(base string) []string {
+	candidates := []string{base}
+	for _, ext := range jsExts {
+		candidates = append(candidates, base+ext)
+	}
+	if ext := filepath.Ext(base); ext == ".js" || ext == ".jsx" || ext == ".mjs" || ext == ".cjs" {
+		stem := strings.TrimSuffix(base, ext)
+		candidates = append(candidates, stem+".ts", stem+".tsx", stem+".mts", stem+".cts")
+	}
+	for _, ext := range jsExts {
+		candidates = append(candidates, filepath.Join(base, "index"+ext))
+	}
+	return r.existing(candidates...)
+}
+
+// 3. Python: relative imports from the importing package, absolute ones
+// from the top of its package tree, the root, or a src folder
+
+func (r *resolver) resolvePython(from, spec string) ([]string, bool) {
+	dots := len(spec) - len(strings.TrimLeft(spec, "."))
+	module := strings.Split(spec[dots:], ".")
+	if module[0] == "" {
+		module = nil
+	}
+
+	var bases []string
+	if dots > 0 {
+		base := filepath.Dir(from)
+		for range dots - 1 {
+			base = filepath.Dir(base)
+		}
+		bases = []string{base}
+	} else {
+		bases = []string{r.packageTop(filepath.Dir(from)), r.root, filepath.Join(r.root, "src")}
+	}
+
+	// "from . import name" may import a name of the package rather than a
+	// module: fall back to the package itself
+	for n := len(module); n >= len(module)-1 && n >= 0; n-- {
+		if n == 0 && dots == 0 {
+			break
+		}
+		for _, base := range bases {
+			path := filepath.Join(append([]string{base}, module[:n]...)...)
+			if found := r.existing(path+".py", filepath.Join(path, "__init__.py")); found != nil {
+				return found, true
+			}
+		}
+	}
+	// Absolute imports not found are installed packages
+	return nil, dots > 0
+}
+
+// packageTop returns the directory above the outermost package (a folder
+// with an __init__.py) that contains dir, which absolute imports start from.
+func (r *resolver) packageTop(dir string) string {
+	for r.files[filepath.Join(dir, "__init__.py")] {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return dir
+}
+
+// 4. Rust: crate::, self:: and super:: paths, to the file of the deepest
+// module they name (a.rs or a/mod.rs), below the src folder of the crate
+
+func (r *resolver) resolveRust(from, spec string) ([]string, bool) {
+	segments := strings.Split(spec, "::")
+	var dir string // folder of the module the path starts from
+	switch segments[0] {
+	case "crate":
+		cargo := nearest(filepath.Dir(from), "Cargo.toml")
+		if cargo == "" {
+			return nil, false
+		}
+		dir = filepath.Join(filepath.Dir(cargo), "src")
+		segments = segments[1:]
+	case "self":
+		dir = rustModuleDir(from)
+		segments = segments[1:]
+	case "super":
+		dir = rustModuleDir(from) // climbed below, like every other super
+	default:
+		return nil, false // another crate
+	}
+	for len(segments) > 0 && segments[0] == "super" {
+		dir = filepath.Dir(dir)
+		segments = segments[1:]
+	}
+
+	// The path ends with an item, or a module, or a glob
+	for n := len(segments); n >= 0; n-- {
+		path := filepath.Join(append([]string{dir}, segments[:n]...)...)
+		candidates := []string{path + ".rs", filepath.Join(path, "mod.rs")}
+		if n == 0 {
+			candidates = append(candidates, filepath.Join(path, "lib.rs"), filepath.Join(path, "main.rs"))
+		}
+		if found := r.existing(candidates...); found != nil {
+			return found, true
+		}
+	}
+	return nil, true
+}
+
+// rustModuleDir returns the folder holding the submodules of the module
+// defined by the Rust file at path: its own folder for lib.rs, main.rs and
+// mod.rs, and a folder named after it otherwise.
+func rustModuleDir(path string) string {
+	switch filepath.Base(path) {
+	case "lib.rs", "main.rs", "mod.rs":
+		return filepath.Dir(path)
+	}
+	return strings.TrimSuffix(path, ".rs")
+}
+
+// 5. HTML and CSS: script, stylesheet and @import references relative to
+// the file, or to the site root when they start with "/": the first folder
+// from the file up to the root where they exist
+
+func (r *resolver) resolveAsset(from, spec string) ([]string, bool) {
+	if i := strings.IndexAny(spec, "?#"); i >= 0 {
+		spec = spec[:i]
+	}
+	if spec == "" || strings.Contains(spec, "://") || strings.HasPrefix(spec, "//") || strings.HasPrefix(spec, "data:") {
+		return nil, false
+	}
+	if !strings.HasPrefix(spec, "/") {
+		return r.asset(from, filepath.Join(filepath.Dir(from), spec)), true
+	}
+	for dir := filepath.Dir(from); ; dir = filepath.Dir(dir) {
+		if found := r.asset(from, filepath.Join(dir, spec)); found != nil {
+			return found, true
+		}
+		if dir == r.root || dir == filepath.Dir(dir) || !strings.HasPrefix(dir, r.root) {
+			return nil, true
+		}
+	}
+}
+
+// asset finds the file at base. Sass and Less imports may leave out the
+// extension and the "_" of partials.
+func (r *resolver) asset(from, base string) []string {
+	ext := filepath.Ext(from)
+	partial := filepath.Join(filepath.Dir(base), "_"+filepath.Base(base))
+	return r.existing(base, base+ext, partial, partial+ext, base+".css")
+}
+
+// 6. C and C++: #include paths relative to the file, else to the first
+// folder from the file up to the root where they exist, directly or in its
+// include or src folder. Not knowing the compiler's include paths, headers
+// not found are taken as system or library headers.
+
+func (r *resolver) resolveInclude(from, spec string) ([]string, bool) {
+	for dir := filepath.Dir(from); ; dir = filepath.Dir(dir) {
+		if found := r.existing(filepath.Join(dir, spec), filepath.Join(dir, "include", spec), filepath.Join(dir, "src", spec)); found != nil {
+			return found, true
+		}
+		if dir == r.root || dir == filepath.Dir(dir) || !strings.HasPrefix(dir, r.root) {
+			return nil, strings.HasPrefix(spec, ".")
+		}
+	}
+}
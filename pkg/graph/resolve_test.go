@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	var files []string
+	for name, content := range map[string]string{
+		"go.mod":                    "module example.com/app\n\nreplace example.com/lib => ./third_party/lib\n",
+		"cmd/main.go":               "",
+		"pkg/api/api.go":            "",
+		"pkg/api/server.go":         "",
+		"pkg/api/api_test.go":       "",
+		"third_party/lib/lib.go":    "",
+		"tsconfig.json":             "{\n  // comments are allowed\n  \"compilerOptions\": {\"baseUrl\": \"web\", \"paths\": {\"@ui/*\": [\"components/*\"]}}\n}\n",
+		"web/app.ts":                "",
+		"web/util.ts":               "",
+		"web/store/index.ts":        "",
+		"web/components/Button.tsx": "",
+		"web/components/Card.vue":   "",
+		"py/app/__init__.py":        "",
+		"py/app/main.py":            "",
+		"py/app/models.py":          "",
+		"py/app/db/__init__.py":     "",
+		"Cargo.toml":                "",
+		"src/main.rs":               "",
+		"src/net/mod.rs":            "",
+		"src/net/tcp.rs":            "",
+		"site/index.html":           "",
+		"site/css/main.css":         "",
+		"site/css/_vars.scss":       "",
+		"site/css/theme.scss":       "",
+		"site/js/app.js":            "",
+		"native/src/a.c":            "",
+		"native/include/a.h":        "",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	tests := []struct {
+		name, from, spec string
+		want             []string
+		wantLocal        bool
+	}{
+		{"Go package", "cmd/main.go", "example.com/app/pkg/api", []string{"pkg/api/api.go", "pkg/api/server.go"}, true},
+		{"Go replace", "cmd/main.go", "example.com/lib", []string{"third_party/lib/lib.go"}, true},
+		{"Go missing package", "cmd/main.go", "example.com/app/pkg/gone", nil, true},
+		{"Go standard library", "cmd/main.go", "fmt", nil, false},
+		{"JS relative", "web/app.ts", "./util", []string{"web/util.ts"}, true},
+		{"JS index", "web/app.ts", "./store", []string{"web/store/index.ts"}, true},
+		{"JS .js for .ts", "web/app.ts", "./util.js", []string{"web/util.ts"}, true},
+		{"JS missing", "web/app.ts", "./gone", nil, true},
+		{"TS paths", "web/app.ts", "@ui/Button", []string{"web/components/Button.tsx"}, true},
+		{"TS baseUrl", "web/app.ts", "util", []string{"web/util.ts"}, true},
+		{"JS component", "web/app.ts", "./components/Card", []string{"web/components/Card.vue"}, true},
+		{"JS package", "web/app.ts", "react", nil, false},
+		{"Python relative", "py/app/main.py", ".models", []string{"py/app/models.py"}, true},
+		{"Python package", "py/app/main.py", "app.db", []string{"py/app/db/__init__.py"}, true},
+		{"Python name of the package", "py/app/main.py", ".", []string{"py/app/__init__.py"}, true},
+		{"Python installed", "py/app/main.py", "requests", nil, false},
+		{"Rust crate path", "src/main.rs", "crate::net::tcp::connect", []string{"src/net/tcp.rs"}, true},
+		{"Rust super", "src/net/tcp.rs", "super::Config", []string{"src/net/mod.rs"}, true},
+		{"Rust self", "src/net/mod.rs", "self::tcp::connect", []string{"src/net/tcp.rs"}, true},
+		{"Rust super of a mod.rs", "src/net/mod.rs", "super::Config", []string{"src/main.rs"}, true},
+		{"Rust other crate", "src/main.rs", "serde::Serialize", nil, false},
+		{"HTML relative", "site/index.html", "js/app.js", []string{"site/js/app.js"}, true},
+		{"HTML site root", "site/index.html", "/css/main.css?v=2", []string{"site/css/main.css"}, true},
+		{"HTML remote", "site/index.html", "https://cdn.example.com/a.js", nil, false},
+		{"Sass partial", "site/css/theme.scss", "vars", []string{"site/css/_vars.scss"}, true},
+		{"C include folder", "native/src/a.c", "a.h", []string{"native/include/a.h"}, true},
+		{"C system header", "native/src/a.c", "stdio.h", nil, false},
+	}
+	r := newResolver(root, files)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, local := r.resolve(filepath.Join(root, filepath.FromSlash(tt.from)), tt.spec)
+			var rels []string
+			for _, f := range got {
+				rel, _ := filepath.Rel(root, f)
+				rels = append(rels, filepath.ToSlash(rel))
+			}
+			slices.Sort(rels)
+			if !slices.Equal(rels, tt.want) || local != tt.wantLocal {
+				t.Errorf("resolve(%s, %q) = %q, %v, want %q, %v", tt.from, tt.spec, rels, local, tt.want, tt.wantLocal)
+			}
+		})
+	}
+}
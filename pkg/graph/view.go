@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ViewOptions selects and groups the nodes of a View.
+type ViewOptions struct {
+	// Scope keeps the imports of the files below this slash path relative
+	// to the root, and the files they import; "" keeps everything
+	Scope string
+	// Dirs makes every directory (a Go package) one node
+	Dirs bool
+	// Depth, when positive, makes the directories that many levels below the
+	// scope (the root for files outside it) one node each, with everything
+	// below them
+	Depth int
+}
+
+// View is a graph of files or directories to draw. Directory nodes end
+// with "/"; the root directory is "./".
+type View struct {
+	Nodes []string   `json:"nodes"`
+	Edges []ViewEdge `json:"edges"`
+}
+
+// ViewEdge is a dependency between two nodes: Count files of From import
+// files of To.
+type ViewEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// View collapses and scopes g into the nodes and edges to draw. Imports
+// within one node are left out.
+func (g *Graph) View(opts ViewOptions) View {
+	scope := strings.Trim(opts.Scope, "/")
+	inScope := func(file string) bool {
+		return scope == "" || scope == "." || file == scope || strings.HasPrefix(file, scope+"/")
+	}
+	scopeDepth := 0
+	if scope != "" && scope != "." {
+		scopeDepth = strings.Count(scope, "/") + 1
+	}
+	node := func(file string) string {
+		if !opts.Dirs && opts.Depth <= 0 {
+			return file
+		}
+		dir := path.Dir(file)
+		if dir == "." {
+			return "./"
+		}
+		depth := opts.Depth
+		if inScope(file) {
+			depth += scopeDepth
+		}
+		if parts := strings.Split(dir, "/"); opts.Depth > 0 && len(parts) > depth {
+			dir = strings.Join(parts[:depth], "/")
+		}
+		return dir + "/"
+	}
+
+	nodes := make(map[string]bool)
+	for _, f := range g.Files {
+		if inScope(f) {
+			nodes[node(f)] = true
+		}
+	}
+	counts := make(map[[2]string]int)
+	seen := make(map[[2]string]bool) // importing file and imported node
+	for _, e := range g.Edges {
+		if !inScope(e.From) {
+			continue
+		}
+		from, to := node(e.From), node(e.To)
+		nodes[to] = true
+		if from != to && !seen[[2]string{e.From, to}] {
+			seen[[2]string{e.From, to}] = true
+			counts[[2]string{from, to}]++
+		}
+	}
+
+	v := View{Nodes: make([]string, 0, len(nodes)), Edges: make([]ViewEdge, 0, len(counts))}
+	for n := range nodes {
+		v.Nodes = append(v.Nodes, n)
+	}
+	sort.Strings(v.Nodes)
+	for k, n := range counts {
+		v.Edges = append(v.Edges, ViewEdge{From: k[0], To: k[1], Count: n})
+	}
+	sort.Slice(v.Edges, func(i, j int) bool {
+		a, b := v.Edges[i], v.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return v
+}
+
+// DOT renders the view as a Graphviz digraph. Edges of several importing
+// files are labeled with their count.
+func (v View) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph dependencies {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box, fontname=\"Helvetica\", fontsize=10];\n")
+	for _, n := range v.Nodes {
+		fmt.Fprintf(&sb, "  %q;\n", n)
+	}
+	for _, e := range v.Edges {
+		if e.Count > 1 {
+			fmt.Fprintf(&sb, "  %q -> %q [label=\"%d\"];\n", e.From, e.To, e.Count)
+		} else {
+			fmt.Fprintf(&sb, "  %q -> %q;\n", e.From, e.To)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the view as a Mermaid flowchart. Nodes get short ids,
+// since paths are not valid Mermaid identifiers.
+func (v View) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(v.Nodes))
+	for i, n := range v.Nodes {
+		ids[n] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&sb, "  %s[\"%s\"]\n", ids[n], strings.ReplaceAll(n, `"`, "#quot;"))
+	}
+	for _, e := range v.Edges {
+		if e.Count > 1 {
+			fmt.Fprintf(&sb, "  %s -->|%d| %s\n", ids[e.From], e.Count, ids[e.To])
+		} else {
+			fmt.Fprintf(&sb, "  %s --> %s\n", ids[e.From], ids[e.To])
+		}
+	}
+	return sb.String()
+}
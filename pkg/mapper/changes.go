@@ -0,0 +1,90 @@
+package mapper
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+// UpdateFiles brings the maps below root up to date after the given paths
+// changed on disk. Created or modified files with an allowed extension are
+// remapped and added to watchlist; deleted files (or every watched file under
+// a deleted directory) lose their maps. The folder maps of each affected
+// directory and its ancestors are then regenerated. It returns the number of
+// files mapped and removed.
+func UpdateFiles(cfg config.Config, root string, m *Manifest, watchlist map[string]bool, paths []string) (mapped, removed int) {
+	dirty := make(map[string]bool)
+
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if IsMapFile(p) {
+			continue
+		}
+		info, err := os.Stat(p)
+		switch {
+		case err != nil:
+			// Gone: the path may have been a file or a whole directory
+			for w := range watchlist {
+				if w == p || strings.HasPrefix(w, p+string(os.PathSeparator)) {
+					RemoveMaps(cfg, w)
+					m.Forget(w)
+					delete(watchlist, w)
+					removed++
+				}
+			}
+			pruneMirror(cfg, p)
+			markDirty(dirty, root, filepath.Dir(p))
+		case info.IsDir():
+			markDirty(dirty, root, p)
+		case isAllowed(cfg, p):
+			if err := GenerateMap(cfg, p); err != nil {
+				log.Printf("❌ %v", err)
+				continue
+			}
+			m.Record(cfg, p)
+			watchlist[p] = true
+			mapped++
+			markDirty(dirty, root, filepath.Dir(p))
+		default:
+			// Not mapped, but still listed in the _level_0 inventory
+			markDirty(dirty, root, filepath.Dir(p))
+		}
+	}
+
+	// Deepest first, so parents embed their children's fresh maps
+	dirs := make([]string, 0, len(dirty))
+	for d := range dirty {
+		dirs = append(dirs, d)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, d := range dirs {
+		if info, err := os.Stat(d); err == nil && info.IsDir() {
+			UpdateFolderMaps(d, watchlist, cfg)
+		}
+	}
+
+	if err := m.Save(); err != nil {
+		log.Printf("❌ Failed to write manifest: %v", err)
+	}
+	return mapped, removed
+}
+
+// isAllowed reports whether the root path belongs to maps files with its
+// extension. Nested roots override the roots around them.
+func isAllowed(cfg config.Config, path string) bool {
+	rc, ok := cfg.RootOf(path)
+	if !ok {
+		return false
+	}
+	ext := filepath.Ext(path)
+	for _, allowed := range rc.AllowedExts {
+		if strings.EqualFold(allowed, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package mapper
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+func TestUpdateFilesUsesOwnRoot(t *testing.T) {
+	root := t.TempDir()
+	goFile := filepath.Join(root, "api", "a.go")
+	pyFile := filepath.Join(root, "api", "b.py")
+	webFile := filepath.Join(root, "web", "c.py")
+	for _, f := range []string{goFile, pyFile, webFile} {
+		writeFile(t, f, "")
+	}
+	cfg := config.Config{Roots: []config.RootConfig{
+		{Path: filepath.Join(root, "api"), AllowedExts: []string{".go"}},
+		{Path: filepath.Join(root, "web"), AllowedExts: []string{".py"}},
+	}}
+
+	watchlist := make(map[string]bool)
+	mapped, _ := UpdateFiles(cfg, root, LoadManifest(cfg, root), watchlist, []string{goFile, pyFile, webFile})
+	var got []string
+	for f := range watchlist {
+		got = append(got, f)
+	}
+	if want := []string{goFile, webFile}; mapped != 2 || !slices.Equal(relPaths(t, root, got), relPaths(t, root, want)) {
+		t.Errorf("mapped %d files %q, want %q", mapped, relPaths(t, root, got), relPaths(t, root, want))
+	}
+}
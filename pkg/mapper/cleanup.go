@@ -14,8 +14,9 @@ func CleanupMaps(cfg config.Config) {
 	log.Println("🧹 Cleaning up orphaned maps...")
 	cleanedCount := 0
 
-	for _, rc := range cfg.Roots {
+	for _, rc := range cfg.WalkRoots() {
 		absRoot, _ := filepath.Abs(rc.Path)
+		ig := NewIgnores(cfg, absRoot)
 		filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return nil
@@ -23,14 +24,18 @@ func CleanupMaps(cfg config.Config) {
 
 			// If it's a directory
 			if info.IsDir() {
-				// Check if ignored
-				if fs.ShouldIgnore(path, info, rc.IgnoredDirs) {
+				// Out-of-tree maps are checked below, against their sources
+				if isOutputDir(cfg, path) {
+					return filepath.SkipDir
+				}
+				// Check if ignored (by name, or by .gitignore / .astrmapignore)
+				if path != absRoot && ig.Ignored(path, true) {
 					// It is ignored! Remove all map files inside relevant to this folder?
 					// Or just remove the folder level maps?
 					// If we skip dir, we can't clean inside.
 					// We must clean the _level_X.map.txt files IN this dir.
 
-					files := []string{"_level_0.map.txt", "_level_1.map.txt", "_level_2.map.txt", "_level_3.map.txt"}
+					files := []string{"_level_0.map.txt", "_level_1.map.txt", "_level_2.map.txt", "_level_3.map.txt", IndexFile}
 					for _, f := range files {
 						mapPath := filepath.Join(path, f)
 						if _, err := os.Stat(mapPath); err == nil {
@@ -46,65 +51,115 @@ func CleanupMaps(cfg config.Config) {
 				}
 			} else {
 				// It is a file
-				if strings.HasSuffix(path, ".map.txt") {
+				if IsMapFile(path) && !isFolderMap(info.Name()) {
 					// Check if the SOURCE file is ignored or deleted
-					// Source file is path without ".map.txt"
-					sourcePath := strings.TrimSuffix(path, ".map.txt")
+					// Source file is path without ".map.txt" / ".map.json"
+					sourcePath := strings.TrimSuffix(strings.TrimSuffix(path, TextMapSuffix), JSONMapSuffix)
 
-					// If source doesn't exist, delete map
-					if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+					if isObsoleteFileMap(sourcePath, rc, ig) {
 						os.Remove(path)
 						cleanedCount++
-						return nil
-					}
-
-					// If source exists but is now ignored (by extension or parent folder)
-					// Parent folder ignore is handled by recursion above potentially?
-					// fs.ShouldIgnore checks path against ignored dirs.
-					sourceInfo, err := os.Stat(sourcePath)
-					if err == nil {
-						if fs.ShouldIgnore(sourcePath, sourceInfo, rc.IgnoredDirs) {
-							os.Remove(path)
-							cleanedCount++
-						} else {
-							// Check extension?
-							ext := strings.ToLower(filepath.Ext(sourcePath))
-							isAllowed := false
-							for _, allowed := range rc.AllowedExts {
-								if allowed == ext {
-									isAllowed = true
-									break
-								}
-							}
-							if !isAllowed {
-								os.Remove(path)
-								cleanedCount++
-							}
-						}
 					}
 				}
 			}
 			return nil
 		})
+
+		// Maps under the output directory mirror their sources below absRoot
+		outDir := OutputDir(cfg, absRoot)
+		if outDir == "" {
+			continue
+		}
+		filepath.Walk(outDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !IsMapFile(info.Name()) {
+				return nil
+			}
+			rel, err := filepath.Rel(outDir, path)
+			if err != nil {
+				return nil
+			}
+			obsolete := false
+			if isFolderMap(info.Name()) {
+				// The folder is gone or ignored now
+				sourceDir := filepath.Join(absRoot, filepath.Dir(rel))
+				sourceInfo, err := os.Stat(sourceDir)
+				obsolete = err != nil || !sourceInfo.IsDir() || ig.Ignored(sourceDir, true)
+			} else {
+				sourcePath := filepath.Join(absRoot, strings.TrimSuffix(strings.TrimSuffix(rel, TextMapSuffix), JSONMapSuffix))
+				obsolete = isObsoleteFileMap(sourcePath, rc, ig)
+			}
+			if obsolete {
+				os.Remove(path)
+				cleanedCount++
+			}
+			return nil
+		})
 	}
 	log.Printf("🧹 Removed %d obsolete map files.", cleanedCount)
 }
 
-// DeepClean deletes ALL .map.txt files recursively from the target directory.
-func DeepClean(targetDir string) {
+// isObsoleteFileMap reports whether the map of sourcePath should go: the
+// source was deleted, or is now ignored (by name, ignore rules or extension).
+func isObsoleteFileMap(sourcePath string, rc config.RootConfig, ig *Ignores) bool {
+	// If source doesn't exist, delete map
+	sourceInfo, err := os.Stat(sourcePath)
+	if os.IsNotExist(err) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+
+	// If source exists but is now ignored (by extension or parent folder)
+	// fs.ShouldIgnore checks the name, ig the ignore rules and parent folders.
+	if fs.ShouldIgnore(sourcePath, sourceInfo, rc.IgnoredDirs) || ig.Ignored(sourcePath, false) {
+		return true
+	}
+	// Check extension?
+	ext := strings.ToLower(filepath.Ext(sourcePath))
+	for _, allowed := range rc.AllowedExts {
+		if allowed == ext {
+			return false
+		}
+	}
+	return true
+}
+
+// DeepClean deletes ALL map files (.map.txt and .map.json) recursively from
+// the target directory, along with its mirror in the output directory. It
+// returns the removed paths; with dryRun nothing is deleted.
+func DeepClean(cfg config.Config, targetDir string, dryRun bool) []string {
 	log.Printf("🧹 Performing Deep Clean in: %s", targetDir)
-	deleted := 0
+	var removed []string
+	remove := func(path string) {
+		if dryRun || os.Remove(path) == nil {
+			removed = append(removed, path)
+		}
+	}
 
+	mirror := outputPath(cfg, targetDir)
 	filepath.Walk(targetDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil
 		}
-		if !info.IsDir() && strings.HasSuffix(path, ".map.txt") {
-			os.Remove(path)
-			deleted++
+		if info.IsDir() && path == mirror && mirror != targetDir {
+			return filepath.SkipDir // emptied below
+		}
+		if !info.IsDir() && IsMapFile(path) {
+			remove(path)
 		}
 		return nil
 	})
 
-	log.Printf("✨ Deep Clean finished. Removed %d files.", deleted)
+	// Without maps the manifest is meaningless
+	if _, err := os.Stat(filepath.Join(targetDir, ManifestFile)); err == nil {
+		remove(filepath.Join(targetDir, ManifestFile))
+	}
+
+	if mirror != targetDir {
+		removed = append(removed, removeMirror(mirror, dryRun)...)
+	}
+
+	log.Printf("✨ Deep Clean finished. Removed %d files.", len(removed))
+	return removed
 }
@@ -0,0 +1,535 @@
+package mapper
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	sfcOpenRe = regexp.MustCompile(`^<(script|style)\b`)
+	sfcLangRe = regexp.MustCompile(`\blang\s*=\s*["']?([\w-]+)`)
+	sfcTypeRe = regexp.MustCompile(`\btype\s*=\s*["']?([\w/+.-]+)`)
+	// <script setup> (Vue), <script context="module"> and <script module> (Svelte)
+	sfcSetupRe  = regexp.MustCompile(`\ssetup\b`)
+	sfcModuleRe = regexp.MustCompile(`\scontext\s*=\s*["']module["']|\smodule\b`)
+
+	// Vue: the compiler macros of <script setup>, and the options of
+	// `export default {}` or `export default defineComponent({})`
+	vuePropsRe   = regexp.MustCompile(`\bdefineProps\s*(?:<|\(\s*[\[{])`)
+	vueEmitsRe   = regexp.MustCompile(`\bdefineEmits\s*(?:<|\(\s*[\[{])`)
+	vueModelRe   = regexp.MustCompile(`\bdefineModel\s*(?:<[^(]*>)?\s*\(`)
+	vueExposeRe  = regexp.MustCompile(`\bdefineExpose\s*\(\s*\{`)
+	vueOptionsRe = regexp.MustCompile(`\bexport\s+default\s+(?:defineComponent\s*\(\s*)?\{`)
+	// Svelte: `export let` props, Svelte 5 `let { a, b } = $props()`, and
+	// the events of createEventDispatcher
+	svelteExportRe     = regexp.MustCompile(`(?m)^[ \t]*export\s+(?:let|var)\s+([\w$]+)`)
+	svelteRunesRe      = regexp.MustCompile(`^\s*(?::[^=;]*)?=\s*\$props\s*\(`)
+	svelteDispatcherRe = regexp.MustCompile(`\bcreateEventDispatcher\s*<`)
+	svelteDispatchRe   = regexp.MustCompile(`\bdispatch\s*\(`)
+	// Astro: `const { a, b } = Astro.props`
+	astroPropsRe  = regexp.MustCompile(`^\s*(?::[^=;]*)?=\s*Astro\.props\b`)
+	sfcDestructRe = regexp.MustCompile(`\b(?:let|const|var)\s*\{`)
+
+	// The name of a property or member, and of an event declared by a call
+	// signature like `(e: 'change', id: number): void`
+	sfcKeyRe  = regexp.MustCompile(`^(?:readonly\s+)?(?:["']([^"']+)["']|([\w$]+))`)
+	sfcCallRe = regexp.MustCompile(`^\(\s*[\w$]+\s*:\s*["']([^"']+)["']`)
+)
+
+// sfcParser maps single-file components: Vue, Svelte and Astro files. Their
+// <script> and <style> blocks, and Astro's "---" frontmatter, are mapped by
+// the parser of their language at their lines in the file, the markup
+// around them like HTML. The props and events the component declares are
+// regions too.
+type sfcParser struct {
+	flavor string // "vue", "svelte" or "astro"
+}
+
+// sfcBlock is a <script> or <style> block of a component, or Astro's
+// frontmatter.
+type sfcBlock struct {
+	tag        string // "script", "style" or "frontmatter"
+	open       string // the opening tag, on one line
+	start, end int    // the lines of the opening and closing tags
+	body, last int    // the first and last lines of the content
+}
+
+// Parse implements Parser.
+func (p *sfcParser) Parse(src []byte) []Region {
+	lines := strings.Split(string(src), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	blocks := sfcBlocks(lines, p.flavor == "astro")
+
+	// The markup, with the blocks blanked so their lines keep their numbers
+	markup := append([]string(nil), lines...)
+	var regions []Region
+	for _, b := range blocks {
+		for i := b.start - 1; i < b.end && i < len(markup); i++ {
+			markup[i] = ""
+		}
+		regions = append(regions, Region{Start: b.start, End: b.end, Kind: KindElement, Name: b.name()})
+		if dep := htmlDepends(b.open); dep != "" {
+			regions = append(regions, Region{Start: b.start, End: b.start, Kind: KindImport, Name: dep})
+		}
+
+		parser := b.parser(p.flavor)
+		if parser == nil || b.body > b.last {
+			continue
+		}
+		body := dedent(lines[b.body-1 : b.last])
+		sub := parser.Parse([]byte(strings.Join(body, "\n")))
+		if b.tag != "style" {
+			script := newSFCScript(body, b.body)
+			regions = append(regions, script.component(p.flavor)...)
+			if p.flavor == "vue" {
+				script.expose(sub)
+			}
+		}
+		for _, r := range sub {
+			r.Start += b.body - 1
+			r.End += b.body - 1
+			regions = append(regions, r)
+		}
+	}
+	return append(regions, htmlParser.Parse([]byte(strings.Join(markup, "\n")))...)
+}
+
+// sfcBlocks finds the script and style blocks of a component, and, when
+// frontmatter is set, the "---" fenced script Astro files start with.
+func sfcBlocks(lines []string, frontmatter bool) []sfcBlock {
+	var blocks []sfcBlock
+	i := 0
+	if frontmatter {
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			i++
+		}
+		if i < len(lines) && strings.TrimSpace(lines[i]) == "---" {
+			b := sfcBlock{tag: "frontmatter", start: i + 1, body: i + 2, end: len(lines), last: len(lines)}
+			for j := i + 1; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == "---" {
+					b.end, b.last = j+1, j
+					break
+				}
+			}
+			blocks = append(blocks, b)
+			i = b.end
+		}
+	}
+
+	for ; i < len(lines); i++ {
+		m := sfcOpenRe.FindStringSubmatch(strings.ToLower(strings.TrimSpace(lines[i])))
+		if m == nil {
+			continue
+		}
+		b := sfcBlock{tag: m[1], start: i + 1}
+		// The opening tag may span lines
+		j, open := i, strings.TrimSpace(lines[i])
+		for !strings.Contains(lines[j], ">") && j+1 < len(lines) {
+			j++
+			open += " " + strings.TrimSpace(lines[j])
+		}
+		if k := strings.IndexByte(open, '>'); k >= 0 {
+			open = open[:k+1]
+		}
+		b.open = open
+
+		closing := "</" + b.tag
+		b.end, b.last = len(lines), len(lines)
+		if strings.Contains(strings.ToLower(lines[j]), closing) {
+			// <script src="app.js"></script>: no content of its own
+			b.end, b.last = j+1, j
+		} else {
+			for k := j + 1; k < len(lines); k++ {
+				if strings.Contains(strings.ToLower(lines[k]), closing) {
+					b.end, b.last = k+1, k
+					break
+				}
+			}
+		}
+		b.body = j + 2
+		blocks = append(blocks, b)
+		i = b.end - 1
+	}
+	return blocks
+}
+
+// name labels the block in maps: its tag, and whether it is the <script
+// setup> of a Vue component or the module script of a Svelte one.
+func (b sfcBlock) name() string {
+	switch {
+	case b.tag == "frontmatter":
+		return "<frontmatter>"
+	case b.tag == "script" && sfcSetupRe.MatchString(b.open):
+		return "<script setup>"
+	case b.tag == "script" && sfcModuleRe.MatchString(b.open):
+		return "<script module>"
+	}
+	return "<" + b.tag + ">"
+}
+
+// parser returns the parser for the language of the block, or nil for
+// blocks that are not code, like JSON data, or that no parser reads, like
+// Sass and Stylus.
+func (b sfcBlock) parser(flavor string) Parser {
+	lang := ""
+	if m := sfcLangRe.FindStringSubmatch(b.open); m != nil {
+		lang = strings.ToLower(m[1])
+	}
+	if b.tag == "style" {
+		switch lang {
+		case "", "css", "postcss":
+			return ParserFor(".css")
+		case "scss", "less":
+			return ParserFor("." + lang)
+		}
+		return nil
+	}
+	if m := sfcTypeRe.FindStringSubmatch(b.open); m != nil {
+		if t := strings.ToLower(m[1]); t != "module" && !strings.HasSuffix(t, "javascript") && !strings.HasSuffix(t, "typescript") {
+			return nil
+		}
+	}
+	switch lang {
+	case "":
+		// Astro scripts are TypeScript
+		if flavor == "astro" {
+			return ParserFor(".ts")
+		}
+		return ParserFor(".js")
+	case "js", "javascript":
+		return ParserFor(".js")
+	case "ts", "typescript":
+		return ParserFor(".ts")
+	case "jsx", "tsx":
+		return ParserFor("." + lang)
+	}
+	return nil
+}
+
+// dedent removes the indentation all non-blank lines share, since
+// declarations are only looked for at the start of a line.
+func dedent(lines []string) []string {
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		if n := len(l) - len(strings.TrimLeft(l, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		if len(l) >= indent && indent > 0 && strings.TrimSpace(l[:indent]) == "" {
+			l = l[indent:]
+		} else if strings.TrimSpace(l) == "" {
+			l = ""
+		}
+		out[i] = l
+	}
+	return out
+}
+
+// sfcScript is the code of a script block, searched for the props and
+// events of the component.
+type sfcScript struct {
+	lines []string
+	text  string // the lines joined
+	bare  string // text without comments
+	code  string // text without comments and literals
+	line  int    // the line of the file text starts on
+}
+
+func newSFCScript(lines []string, line int) *sfcScript {
+	lex := braceLexer{syntax: jsSyntax}
+	bare := make([]string, len(lines))
+	code := make([]string, len(lines))
+	for i, l := range lines {
+		inComment := lex.mode == lexBlockComment
+		code[i] = lex.code(l)
+		bare[i] = cStripComments(l, code[i], inComment)
+	}
+	return &sfcScript{
+		lines: lines,
+		text:  strings.Join(lines, "\n"),
+		bare:  strings.Join(bare, "\n"),
+		code:  strings.Join(code, "\n"),
+		line:  line,
+	}
+}
+
+// lineAt returns the line of the file holding text[i].
+func (s *sfcScript) lineAt(i int) int {
+	return s.line + strings.Count(s.text[:i], "\n")
+}
+
+// component returns the props and events the script declares.
+func (s *sfcScript) component(flavor string) []Region {
+	var regions []Region
+	switch flavor {
+	case "vue":
+		for _, loc := range vuePropsRe.FindAllStringIndex(s.code, -1) {
+			regions = append(regions, s.declared(loc[1]-1, KindProp)...)
+		}
+		for _, loc := range vueEmitsRe.FindAllStringIndex(s.code, -1) {
+			regions = append(regions, s.declared(loc[1]-1, KindEvent)...)
+		}
+		for _, loc := range vueModelRe.FindAllStringIndex(s.code, -1) {
+			// defineModel("count") is the "count" prop, defineModel() is v-model's
+			name := s.stringAt(loc[1])
+			if name == "" {
+				name = "modelValue"
+			}
+			line := s.lineAt(loc[0])
+			regions = append(regions, Region{
+				Start: line, End: line, Kind: KindProp, Name: name, Exported: true,
+				Signature: s.lineSignature(loc[0]), Doc: s.doc(line),
+			})
+		}
+		// The options API
+		if loc := vueOptionsRe.FindStringIndex(s.code); loc != nil {
+			for _, e := range s.entries(loc[1]-1, false) {
+				kind := KindProp
+				switch s.key(e) {
+				case "props":
+				case "emits":
+					kind = KindEvent
+				default:
+					continue
+				}
+				if open := strings.IndexAny(s.code[e[0]:e[1]], "[{"); open >= 0 {
+					regions = append(regions, s.members(e[0]+open, false, kind)...)
+				}
+			}
+		}
+
+	case "svelte":
+		for _, m := range svelteExportRe.FindAllStringSubmatchIndex(s.code, -1) {
+			line := s.lineAt(m[2])
+			regions = append(regions, Region{
+				Start: line, End: line, Kind: KindProp, Name: s.code[m[2]:m[3]], Exported: true,
+				Signature: s.lineSignature(m[2]), Doc: s.doc(line),
+			})
+		}
+		regions = append(regions, s.destructured(svelteRunesRe)...)
+		if loc := svelteDispatcherRe.FindStringIndex(s.code); loc != nil {
+			regions = append(regions, s.declared(loc[1]-1, KindEvent)...)
+		} else {
+			// Untyped dispatchers: the events dispatch() is called with
+			seen := make(map[string]bool)
+			for _, loc := range svelteDispatchRe.FindAllStringIndex(s.code, -1) {
+				if name := s.stringAt(loc[1]); name != "" && !seen[name] {
+					seen[name] = true
+					line := s.lineAt(loc[0])
+					regions = append(regions, Region{Start: line, End: line, Kind: KindEvent, Name: name, Exported: true})
+				}
+			}
+		}
+
+	case "astro":
+		if open := s.typeLiteral("Props"); open >= 0 {
+			regions = append(regions, s.members(open, true, KindProp)...)
+		} else {
+			regions = append(regions, s.destructured(astroPropsRe)...)
+		}
+	}
+	return regions
+}
+
+// declared returns the props or events declared by the type argument or
+// the literal argument at code[open]: `defineProps<{ title: string }>()`,
+// `defineProps<Props>()`, `defineEmits(["change"])`...
+func (s *sfcScript) declared(open int, kind Kind) []Region {
+	if s.code[open] != '<' {
+		return s.members(open, false, kind)
+	}
+	arg := strings.TrimLeft(s.code[open+1:], " \t\n")
+	at := len(s.code) - len(arg)
+	if strings.HasPrefix(arg, "{") {
+		return s.members(at, true, kind)
+	}
+	name := arg[:len(arg)-len(strings.TrimLeft(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_$"))]
+	if name == "" {
+		return nil
+	}
+	if open := s.typeLiteral(name); open >= 0 {
+		return s.members(open, true, kind)
+	}
+	return nil
+}
+
+// typeLiteral returns the index of the "{" of the interface or object type
+// named name, or -1 when the script does not declare it.
+func (s *sfcScript) typeLiteral(name string) int {
+	re := regexp.MustCompile(`\b(?:interface\s+` + regexp.QuoteMeta(name) + `\b[^{]*|type\s+` + regexp.QuoteMeta(name) + `\s*=\s*)\{`)
+	if loc := re.FindStringIndex(s.code); loc != nil {
+		return loc[1] - 1
+	}
+	return -1
+}
+
+// destructured returns the props destructured by `let { a, b } = ...`
+// where what follows the pattern matches source.
+func (s *sfcScript) destructured(source *regexp.Regexp) []Region {
+	for _, loc := range sfcDestructRe.FindAllStringIndex(s.code, -1) {
+		open := loc[1] - 1
+		if end := s.closing(open); end >= 0 && source.MatchString(s.code[end+1:]) {
+			return s.members(open, false, KindProp)
+		}
+	}
+	return nil
+}
+
+// members returns a region for each named entry of the literal at
+// code[open].
+func (s *sfcScript) members(open int, types bool, kind Kind) []Region {
+	var regions []Region
+	for _, e := range s.entries(open, types) {
+		name := s.key(e)
+		if name == "" {
+			continue
+		}
+		start, end := s.lineAt(e[0]), s.lineAt(e[1])
+		sig := strings.Join(strings.Fields(s.bare[e[0]:e[1]]), " ")
+		if len(sig) > 80 {
+			sig = signature(s.bare[e[0]:s.lineEnd(e[0])]) + " ..."
+		} else if strings.Trim(sig, `"'`) == name {
+			sig = "" // an item of an array of names
+		}
+		regions = append(regions, Region{
+			Start: start, End: end, Kind: kind, Name: name, Exported: true,
+			Signature: sig, Doc: s.doc(start),
+		})
+	}
+	return regions
+}
+
+// key returns the name of an entry: a property name, a string item of an
+// array, or the event of a call signature. Spreads have none.
+func (s *sfcScript) key(e [2]int) string {
+	text := s.bare[e[0]:e[1]]
+	if m := sfcCallRe.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	if m := sfcKeyRe.FindStringSubmatch(text); m != nil {
+		return m[1] + m[2]
+	}
+	return ""
+}
+
+// entries splits the literal opened by the bracket at code[open] into the
+// ranges of its entries, without the comments around them. The members of
+// a type literal (types) may also end at ";" or a line break.
+func (s *sfcScript) entries(open int, types bool) [][2]int {
+	end := s.closing(open)
+	if end < 0 {
+		return nil
+	}
+	var out [][2]int
+	from, depth := open+1, 0
+	emit := func(to int) {
+		seg := s.bare[from:to]
+		trimmed := strings.TrimSpace(seg)
+		if trimmed == "" {
+			return
+		}
+		start := from + strings.Index(seg, trimmed)
+		out = append(out, [2]int{start, start + len(trimmed)})
+	}
+	for i := open + 1; i < end; i++ {
+		switch c := s.code[i]; {
+		case c == '(' || c == '[' || c == '{' || c == '<' && types:
+			depth++
+		case c == ')' || c == ']' || c == '}' || c == '>' && types && s.code[i-1] != '=':
+			depth--
+		case depth == 0 && (c == ',' || types && (c == ';' || c == '\n')):
+			emit(i)
+			from = i + 1
+		}
+	}
+	emit(end)
+	return out
+}
+
+// closing returns the index of the bracket closing the one at code[open],
+// or -1. Angle brackets only count after a "<" one, and not in "=>".
+func (s *sfcScript) closing(open int) int {
+	angles := s.code[open] == '<'
+	depth := 0
+	for i := open; i < len(s.code); i++ {
+		switch c := s.code[i]; {
+		case c == '(' || c == '[' || c == '{' || c == '<' && angles:
+			depth++
+		case c == ')' || c == ']' || c == '}' || c == '>' && angles && s.code[i-1] != '=':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// lineSignature returns the statement on the line holding text[i] as a
+// signature, without comments and the closing ";".
+func (s *sfcScript) lineSignature(i int) string {
+	start := strings.LastIndexByte(s.text[:i], '\n') + 1
+	return signature(strings.TrimSuffix(strings.TrimSpace(s.bare[start:s.lineEnd(i)]), ";"))
+}
+
+// lineEnd returns the index of the end of the line holding text[i].
+func (s *sfcScript) lineEnd(i int) int {
+	if end := strings.IndexByte(s.text[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(s.text)
+}
+
+// stringAt returns the value of the string literal at or after text[i],
+// skipping whitespace, or "" when there is none.
+func (s *sfcScript) stringAt(i int) string {
+	rest := strings.TrimLeft(s.bare[i:], " \t\n")
+	if rest == "" || rest[0] != '"' && rest[0] != '\'' && rest[0] != '`' {
+		return ""
+	}
+	if end := strings.IndexByte(rest[1:], rest[0]); end >= 0 {
+		return rest[1 : end+1]
+	}
+	return ""
+}
+
+// doc returns the comment right above the given line of the file.
+func (s *sfcScript) doc(line int) string {
+	i := line - s.line - 1
+	for i >= 0 && i < len(s.lines) {
+		t := strings.TrimSpace(s.lines[i])
+		if !strings.HasPrefix(t, "//") && !strings.HasPrefix(t, "/*") && !strings.HasPrefix(t, "*") {
+			break
+		}
+		i--
+	}
+	if i+1 >= line-s.line {
+		return ""
+	}
+	return commentText(s.lines[i+1 : line-s.line])
+}
+
+// expose marks the functions and values a Vue <script setup> hands to
+// defineExpose as exported: they are what a parent reaches through a ref.
+func (s *sfcScript) expose(regions []Region) {
+	loc := vueExposeRe.FindStringIndex(s.code)
+	if loc == nil {
+		return
+	}
+	exposed := make(map[string]bool)
+	for _, e := range s.entries(loc[1]-1, false) {
+		exposed[s.key(e)] = true
+	}
+	for i, r := range regions {
+		if r.Parent == "" && exposed[r.Name] && r.Kind != KindImport {
+			regions[i].Exported = true
+		}
+	}
+}
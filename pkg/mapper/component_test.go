@@ -0,0 +1,49 @@
+package mapper
+
+import "testing"
+
+func TestComponentParsers(t *testing.T) {
+	runParserTests(t, []parserTest{
+		{"Vue", ".vue", `<template>
+  <div>{{ label }}</div>
+</template>
+
+<script setup lang="ts">
+const props = defineProps<{ label: string }>()
+const emit = defineEmits(['close'])
+</script>
+
+<style scoped>
+div { color: red; }
+</style>
+`, []string{
+			"1-3 <template>",
+			"2-2 <div>",
+			"5-8 <script setup>",
+			"6-6 🎛️ label",
+			"7-7 📣 close",
+			"10-12 <style>",
+		}},
+		{"Svelte", ".svelte", `<script>
+  export let name;
+</script>
+
+<h1>{name}</h1>
+`, []string{
+			"1-3 <script>",
+			"2-2 🎛️ name",
+		}},
+		{"Astro", ".astro", `---
+interface Props {
+  title: string
+}
+---
+<main>{Astro.props.title}</main>
+`, []string{
+			"1-5 <frontmatter>",
+			"2-4 📄 Props",
+			"3-3 🎛️ title",
+			"6-6 <main>",
+		}},
+	})
+}
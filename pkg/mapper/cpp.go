@@ -0,0 +1,724 @@
+package mapper
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+var (
+	cDirectiveRe = regexp.MustCompile(`^#\s*(\w+)\s*(.*)$`)
+	// #include <stdio.h>, #include "util/str.h", #import "Foo.h"
+	cIncludeRe = regexp.MustCompile(`^(?:include|import|include_next)\s*[<"]([^>"]+)[>"]`)
+	// #define NAME value, #define NAME(a, b) body; include guards have no body
+	cDefineRe    = regexp.MustCompile(`^([A-Za-z_]\w*)(\([^)]*\))?\s*(\S?)`)
+	cNamespaceRe = regexp.MustCompile(`^(?:inline\s+)?namespace\b\s*([\w:]*)`)
+	cTypeRe      = regexp.MustCompile(`^(?:typedef\s+)?(?:(?:static|inline|extern|export|const|volatile)\s+)*(struct|class|union|enum)\b\s*(.*)$`)
+	cUsingRe     = regexp.MustCompile(`^using\s+([A-Za-z_]\w*)\s*=`)
+	cFnPtrRe     = regexp.MustCompile(`\(\s*[\w:]*\*\s*([A-Za-z_]\w*)\s*\)`)
+	cIdentRe     = regexp.MustCompile(`[A-Za-z_]\w*`)
+	cQualIdentRe = regexp.MustCompile(`[A-Za-z_][\w:]*`)
+	cStaticRe    = regexp.MustCompile(`\bstatic\b`)
+	// public:, protected:, Qt's "public slots:" and "signals:"
+	cAccessRe = regexp.MustCompile(`^(?:(public|protected|private)(?:\s+(?:slots|Q_SLOTS))?|signals|Q_SIGNALS)$`)
+	// The name of a function, qualified or not, at the end of what precedes
+	// its parameters: "Foo<T>::bar", "~Foo", "operator==", "operator bool"
+	cFuncNameRe = regexp.MustCompile(`((?:[A-Za-z_]\w*(?:<[^()]*>)?\s*::\s*)*(?:~\s*[A-Za-z_]\w*|operator\s*(?:\(\)|\[\]|[^\w\s]+|[A-Za-z_][\w:\s*&<>]*)|[A-Za-z_]\w*))$`)
+	// A macro alone, like G_DEFINE_TYPE(...), Q_OBJECT or __BEGIN_DECLS,
+	// which needs no semicolon and would otherwise run into the next
+	// declaration. A lone BOOL is more likely a return type.
+	cMacroCallRe = regexp.MustCompile(`^_*(?:[A-Z][A-Z0-9_]*\s*\(.*\)|[A-Z][A-Z0-9]*(?:_[A-Z0-9]+)+)$`)
+	cMacroNameRe = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+	// TEST(Suite, Name), TEST_F(Fixture, Name)... (GoogleTest)
+	cGTestRe = regexp.MustCompile(`^(?:TEST|TEST_F|TEST_P|TYPED_TEST|TYPED_TEST_P)\s*\(\s*(\w+)\s*,\s*(\w+)\s*\)`)
+	// TEST_CASE("name"), SCENARIO("name"), TEST_CASE_METHOD(Fixture, "name") (Catch2, doctest)
+	cCatchRe = regexp.MustCompile(`^(?:TEST_CASE|SCENARIO|TEST_CASE_METHOD|TEST_CASE_FIXTURE)\s*\((?:\s*\w+\s*,)?\s*"([^"]*)"`)
+	// BOOST_AUTO_TEST_CASE(name), BOOST_FIXTURE_TEST_CASE(name, Fixture)
+	cBoostRe = regexp.MustCompile(`^BOOST_(?:AUTO|FIXTURE|DATA)_TEST_CASE\s*\(\s*(\w+)`)
+)
+
+// cKeywords are words followed by parentheses that never name a function.
+var cKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "return": true, "sizeof": true,
+	"catch": true, "do": true, "else": true, "case": true, "new": true, "delete": true,
+	"throw": true, "decltype": true, "alignof": true, "static_assert": true, "typeof": true,
+	"void": true, "int": true, "char": true, "defined": true,
+}
+
+// cParser maps C and C++ sources. Unlike the line parsers it reads whole
+// statements, so a declaration may span lines: a return type above the
+// name, parameters over several lines, a template<> prefix, a brace on its
+// own line. Only the first branch of #if/#else is read, since both usually
+// open the same braces. Headers also map function prototypes and macros,
+// which are their API; in sources they would only repeat the definitions.
+type cParser struct {
+	header bool
+}
+
+type cScopeKind int
+
+const (
+	cFile      cScopeKind = iota
+	cNamespace            // namespace ... { }
+	cExtern               // extern "C" { }
+	cClass                // struct, class and union bodies
+	cBody                 // function bodies, enum bodies, initializers: nothing declared
+)
+
+type cScope struct {
+	kind   cScopeKind
+	region int    // index in regions, or -1
+	name   string // of a class, the parent of its methods
+	public bool   // current access of a class body
+	anon   bool   // an anonymous namespace, whose names are not exported
+}
+
+// declares reports whether declarations inside the scope are mapped.
+func (s cScope) declares() bool {
+	return s.kind != cBody
+}
+
+// cCond is an open #if: whether its current branch is skipped, and whether
+// a branch was already read.
+type cCond struct {
+	skip, taken bool
+}
+
+// Parse implements Parser.
+func (p *cParser) Parse(src []byte) []Region {
+	var regions []Region
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	lex := braceLexer{syntax: cSyntax}
+
+	scopes := []cScope{{kind: cFile, region: -1}}
+	var conds []cCond
+	skipping := func() bool {
+		for _, c := range conds {
+			if c.skip {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The statement being read, as code (comments and literals blanked) and
+	// as text (comments blanked) for the signature
+	var pendCode, pendText strings.Builder
+	pendStart, pendParen, pendBrace := 0, 0, 0
+	var pendDoc, doc []string
+	reset := func() {
+		pendCode.Reset()
+		pendText.Reset()
+		pendStart, pendParen, pendBrace = 0, 0, 0
+		pendDoc = nil
+	}
+	unnamed := -1      // a "typedef struct {" region named after its closing brace
+	continued := false // the previous line was a directive ending in "\"
+	define := -1       // the region of the #define being continued
+	guard := ""        // the name of the last #ifndef, an include guard if defined next
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		inCode, inComment := lex.inCode(), lex.mode == lexBlockComment
+		code := lex.code(text)
+
+		// Preprocessor lines, with their "\" continuations
+		if continued {
+			continued = strings.HasSuffix(trimmed, "\\")
+			if define >= 0 {
+				regions[define].End = lineNum
+			}
+			continue
+		}
+		define = -1
+		if inCode && strings.HasPrefix(trimmed, "#") {
+			continued = strings.HasSuffix(trimmed, "\\")
+			directive := strings.TrimSpace(cStripComments(text, code, inComment))
+			m := cDirectiveRe.FindStringSubmatch(directive)
+			if m == nil {
+				doc = nil
+				continue
+			}
+			arg := strings.TrimSpace(m[2])
+			if m[1] != "define" {
+				guard = ""
+			}
+			switch m[1] {
+			case "if", "ifdef", "ifndef":
+				if m[1] == "ifndef" {
+					guard = arg
+				}
+				switch {
+				case skipping():
+					conds = append(conds, cCond{skip: true, taken: true})
+				case m[1] == "if" && (arg == "0" || arg == "false"):
+					conds = append(conds, cCond{skip: true})
+				default:
+					conds = append(conds, cCond{taken: true})
+				}
+			case "elif", "elifdef", "elifndef", "else":
+				if len(conds) > 0 {
+					c := &conds[len(conds)-1]
+					if c.taken {
+						c.skip = true
+					} else if m[1] != "elif" || (arg != "0" && arg != "false") {
+						c.skip, c.taken = false, true
+					}
+				}
+			case "endif":
+				if len(conds) > 0 {
+					conds = conds[:len(conds)-1]
+				}
+			}
+			if skipping() {
+				doc = nil
+				continue
+			}
+			directive = strings.TrimSpace(strings.TrimPrefix(directive, "#"))
+			if dep := cIncludeRe.FindStringSubmatch(directive); dep != nil {
+				regions = append(regions, Region{Start: lineNum, End: lineNum, Kind: KindImport, Name: dep[1]})
+			} else if m[1] == "define" {
+				if d := cDefineRe.FindStringSubmatch(m[2]); d != nil && d[1] != guard && (d[2] != "" || p.header && d[3] != "") {
+					regions = append(regions, Region{
+						Start: lineNum, End: lineNum, Kind: KindMacro, Name: d[1], Exported: p.header,
+						Doc: commentText(doc), Signature: signature("#" + strings.TrimSuffix(directive, "\\")),
+					})
+					if continued {
+						define = len(regions) - 1
+					}
+				}
+			}
+			doc = nil
+			continue
+		}
+		if skipping() {
+			continue
+		}
+
+		// Comments and blank lines
+		if strings.TrimSpace(code) == "" {
+			switch {
+			case trimmed == "" && !inComment:
+				doc = nil
+			case inCode && strings.Contains(text, "//"):
+				if name, ok := markerName(text); ok {
+					if name != "" {
+						regions = append(regions, Region{Start: lineNum, End: lineNum, Kind: KindMarker, Name: name})
+					}
+					doc = nil
+					break
+				}
+				fallthrough
+			default:
+				doc = append(doc, text)
+			}
+			continue
+		}
+
+		sig := cStripComments(text, code, inComment)
+		for i := 0; i < len(code); i++ {
+			c := code[i]
+			top := &scopes[len(scopes)-1]
+			if !top.declares() {
+				switch c {
+				case '{':
+					scopes = append(scopes, cScope{kind: cBody, region: -1})
+				case '}':
+					if len(scopes) > 1 {
+						if r := top.region; r >= 0 {
+							regions[r].End = lineNum
+							if regions[r].Name == "" {
+								unnamed = r
+							}
+						}
+						scopes = scopes[:len(scopes)-1]
+					}
+				}
+				continue
+			}
+
+			inParens := pendParen > 0 || pendBrace > 0
+			switch {
+			case c == '{' && pendBrace > 0:
+				pendBrace++
+			case c == '{' && !inParens && cInitBrace(pendCode.String()):
+				// Foo() : a{1}, b{2} { ... }: a member initializer, not the body
+				pendBrace++
+			case c == '{' && !inParens:
+				header := cClean(pendCode.String())
+				region := Region{Start: pendStart, Doc: commentText(pendDoc), Signature: signature(pendText.String())}
+				if pendStart == 0 {
+					region.Start = lineNum
+				}
+				reset()
+				scope := p.classify(header, region, scopes, &regions)
+				if scope.region >= 0 {
+					unnamed = -1
+				}
+				scopes = append(scopes, scope)
+				continue
+			case c == '}' && pendBrace > 0:
+				pendBrace--
+			case c == '}' && inParens:
+			case c == '}':
+				reset()
+				if len(scopes) > 1 {
+					if r := top.region; r >= 0 {
+						regions[r].End = lineNum
+						if regions[r].Name == "" {
+							unnamed = r
+						}
+					}
+					scopes = scopes[:len(scopes)-1]
+				}
+				continue
+			case c == ';' && !inParens:
+				header := cClean(pendCode.String())
+				region := Region{Start: pendStart, End: lineNum, Doc: commentText(pendDoc), Signature: signature(pendText.String())}
+				reset()
+				if unnamed >= 0 {
+					// typedef struct { ... } Name;
+					if name := cIdentRe.FindString(header); name != "" && regions[unnamed].Name == "" {
+						regions[unnamed].Name = name
+					}
+					unnamed = -1
+					continue
+				}
+				if region.Start > 0 {
+					p.declaration(header, region, scopes, &regions)
+				}
+				continue
+			case c == ':' && top.kind == cClass && !inParens &&
+				(i+1 >= len(code) || code[i+1] != ':') && (i == 0 || code[i-1] != ':'):
+				if m := cAccessRe.FindStringSubmatch(strings.TrimSpace(pendCode.String())); m != nil {
+					top.public = m[1] != "private" && m[1] != "protected"
+					reset()
+					continue
+				}
+			case c == '(':
+				pendParen++
+			case c == ')' && pendParen > 0:
+				pendParen--
+			}
+
+			if pendStart == 0 {
+				if c == ' ' || c == '\t' {
+					continue
+				}
+				pendStart, pendDoc = lineNum, doc
+			}
+			pendCode.WriteByte(c)
+			pendText.WriteByte(sig[i])
+		}
+		doc = nil
+		if pendStart > 0 {
+			if pendParen == 0 && pendBrace == 0 && cMacroCallRe.MatchString(strings.TrimSpace(pendCode.String())) &&
+				!cIsTestMacro(pendCode.String()) {
+				reset()
+			} else {
+				pendCode.WriteByte(' ')
+				pendText.WriteByte(' ')
+			}
+		}
+	}
+
+	// Close scopes left open at end of file
+	for _, s := range scopes {
+		if s.region >= 0 && regions[s.region].End == 0 {
+			regions[s.region].End = lineNum
+		}
+	}
+	// Anonymous types never named by a typedef are left out
+	kept := regions[:0]
+	for _, r := range regions {
+		if r.Name != "" || r.Kind == KindImport {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// classify maps a header followed by "{" and returns the scope its body
+// opens: a namespace, a type, a function or a test, or a plain block.
+func (p *cParser) classify(header string, region Region, scopes []cScope, regions *[]Region) cScope {
+	top := scopes[len(scopes)-1]
+	add := func(kind Kind, name string) int {
+		region.Kind, region.Name = kind, name
+		if !kind.hasSignature() {
+			region.Signature = ""
+		}
+		*regions = append(*regions, region)
+		return len(*regions) - 1
+	}
+	body := cScope{kind: cBody, region: -1}
+	header = cStripTemplate(header)
+
+	switch {
+	case header == "":
+		return body
+	case header == "extern":
+		return cScope{kind: cExtern, region: -1}
+	case cNamespaceRe.MatchString(header):
+		name := cNamespaceRe.FindStringSubmatch(header)[1]
+		scope := cScope{kind: cNamespace, anon: name == ""}
+		if name == "" {
+			name = "(anonymous)"
+		}
+		region.Exported = !scope.anon && !inAnonymous(scopes)
+		scope.region = add(KindModule, name)
+		return scope
+	}
+
+	if m := cTypeRe.FindStringSubmatch(header); m != nil && !strings.Contains(header, "(") {
+		name := cTypeName(m[2])
+		typedef := strings.HasPrefix(header, "typedef")
+		if name == "" && !typedef {
+			// An anonymous struct or union member, or a variable of one
+			if m[1] == "enum" {
+				return body
+			}
+			return cScope{kind: cClass, region: -1, public: true}
+		}
+		region.Exported = cExported(top, header, scopes)
+		if m[1] == "enum" {
+			body.region = add(KindType, name)
+			return body
+		}
+		return cScope{kind: cClass, region: add(KindClass, name), name: name, public: m[1] != "class"}
+	}
+
+	if kind, name, parent, ok := cTest(header, region.Signature); ok {
+		region.Parent = parent
+		body.region = add(kind, name)
+		return body
+	}
+	if name, parent, ok := cFunction(header, top); ok {
+		region.Exported = cExported(top, header, scopes)
+		kind := KindFunction
+		if top.kind == cClass {
+			parent = top.name
+		}
+		if parent != "" {
+			kind, region.Parent = KindMethod, parent
+		}
+		body.region = add(kind, name)
+	}
+	return body
+}
+
+// declaration maps a statement ending in ";": typedefs and using aliases,
+// and the function prototypes of headers and class bodies.
+func (p *cParser) declaration(header string, region Region, scopes []cScope, regions *[]Region) {
+	top := scopes[len(scopes)-1]
+	header = cStripTemplate(header)
+	region.Exported = cExported(top, header, scopes)
+	switch {
+	case strings.HasPrefix(header, "typedef "):
+		name := ""
+		if m := cFnPtrRe.FindStringSubmatch(header); m != nil {
+			name = m[1]
+		} else {
+			header, _, _ = strings.Cut(header, "[")
+			if ids := cIdentRe.FindAllString(header, -1); len(ids) > 1 {
+				name = ids[len(ids)-1]
+			}
+		}
+		if name != "" {
+			region.Kind, region.Name = KindType, name
+			*regions = append(*regions, region)
+		}
+	case cUsingRe.MatchString(header):
+		region.Kind, region.Name = KindType, cUsingRe.FindStringSubmatch(header)[1]
+		*regions = append(*regions, region)
+	case p.header || top.kind == cClass:
+		if strings.HasPrefix(header, "friend ") || strings.HasSuffix(header, "= delete") {
+			return
+		}
+		name, parent, ok := cFunction(header, top)
+		if !ok {
+			return
+		}
+		region.Kind, region.Name = KindFunction, name
+		if top.kind == cClass {
+			parent = top.name
+		}
+		if parent != "" {
+			region.Kind, region.Parent = KindMethod, parent
+		}
+		*regions = append(*regions, region)
+	}
+}
+
+// cFunction returns the name of the function a header declares, and the
+// class it is qualified with, as in "void Foo::bar() const". A name without
+// a return type only declares a function as a constructor or destructor,
+// or when qualified; otherwise it is a macro invocation.
+func cFunction(header string, top cScope) (name, parent string, ok bool) {
+	open := strings.IndexByte(header, '(')
+	if open < 0 {
+		return "", "", false
+	}
+	before := strings.TrimSpace(header[:open])
+	if strings.HasSuffix(before, "operator") && strings.HasPrefix(header[open:], "()") {
+		before += "()"
+	}
+	m := cFuncNameRe.FindStringSubmatch(before)
+	if m == nil {
+		return "", "", false
+	}
+	rest := strings.TrimSpace(before[:len(before)-len(m[1])])
+	if strings.HasPrefix(header[open:], "((") && cMacroNameRe.MatchString(m[1]) && rest != "" {
+		// A macro wrapping the parameters, like zlib's "deflate OF((...))"
+		before = rest
+		if m = cFuncNameRe.FindStringSubmatch(before); m == nil {
+			return "", "", false
+		}
+		rest = strings.TrimSpace(before[:len(before)-len(m[1])])
+	}
+	if strings.Contains(strings.ReplaceAll(rest, "operator", ""), "=") || strings.HasSuffix(rest, "::") {
+		return "", "", false
+	}
+
+	qualified := strings.Join(strings.Fields(m[1]), "")
+	name = qualified
+	if !strings.HasPrefix(qualified, "operator") {
+		if i := strings.LastIndex(qualified, "::"); i >= 0 {
+			name, parent = qualified[i+2:], stripTemplateArgs(qualified[:i])
+		}
+	}
+	if cKeywords[name] {
+		return "", "", false
+	}
+	ctor := top.kind == cClass && strings.TrimPrefix(name, "~") == top.name
+	if rest == "" && parent == "" && !ctor && !strings.HasPrefix(name, "operator") {
+		return "", "", false
+	}
+	return name, parent, true
+}
+
+// cTest recognizes the test cases of GoogleTest, Catch2, doctest and
+// Boost.Test, which are macro invocations followed by a body.
+func cTest(header, sig string) (kind Kind, name, parent string, ok bool) {
+	if m := cGTestRe.FindStringSubmatch(header); m != nil {
+		return KindTest, m[2], m[1], true
+	}
+	if m := cCatchRe.FindStringSubmatch(sig); m != nil {
+		return KindTest, m[1], "", true
+	}
+	if m := cBoostRe.FindStringSubmatch(header); m != nil {
+		return KindTest, m[1], "", true
+	}
+	return "", "", "", false
+}
+
+func cIsTestMacro(code string) bool {
+	code = strings.TrimSpace(code)
+	return cGTestRe.MatchString(code) || cBoostRe.MatchString(code) ||
+		strings.HasPrefix(code, "TEST_CASE") || strings.HasPrefix(code, "SCENARIO")
+}
+
+// cExported reports whether a declaration is visible outside its file or
+// class: public members, and names neither static nor in an anonymous
+// namespace.
+func cExported(top cScope, header string, scopes []cScope) bool {
+	if top.kind == cClass {
+		return top.public
+	}
+	if open := strings.IndexByte(header, '('); open >= 0 {
+		header = header[:open]
+	}
+	return !cStaticRe.MatchString(header) && !inAnonymous(scopes)
+}
+
+func inAnonymous(scopes []cScope) bool {
+	for _, s := range scopes {
+		if s.anon {
+			return true
+		}
+	}
+	return false
+}
+
+// cTypeName returns the name of a struct, class, union or enum from what
+// follows its keyword: "EXPORT Foo final : public Bar" gives "Foo".
+func cTypeName(rest string) string {
+	rest = strings.TrimPrefix(strings.TrimPrefix(rest, "class "), "struct ")
+	for i := 0; i < len(rest); i++ {
+		if rest[i] == ':' && (i+1 == len(rest) || rest[i+1] != ':') && (i == 0 || rest[i-1] != ':') {
+			rest = rest[:i]
+			break
+		}
+	}
+	rest, _, _ = strings.Cut(rest, "<")
+	var name string
+	for _, id := range cQualIdentRe.FindAllString(rest, -1) {
+		if id != "final" {
+			name = id
+		}
+	}
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		name = name[i+2:]
+	}
+	return name
+}
+
+// cInitBrace reports whether a "{" after a statement starts a braced member
+// initializer ("Foo() : a{1}") rather than the constructor body.
+func cInitBrace(pending string) bool {
+	close := -1
+	depth := 0
+	for i, c := range pending {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth--; depth == 0 {
+				close = i
+				break
+			}
+		}
+	}
+	if close < 0 {
+		return false
+	}
+	after := strings.TrimSpace(pending[close+1:])
+	if after == "" || !isWordByte(after[len(after)-1]) && after[len(after)-1] != '>' {
+		return false
+	}
+	return strings.Contains(strings.ReplaceAll(after, "::", ""), ":")
+}
+
+// cClean collapses whitespace and drops the attributes of a header:
+// [[nodiscard]], __attribute__((...)), __declspec(...) and alignas(...).
+func cClean(header string) string {
+	header = strings.Join(strings.Fields(header), " ")
+	for strings.Contains(header, "[[") {
+		i := strings.Index(header, "[[")
+		j := strings.Index(header[i:], "]]")
+		if j < 0 {
+			break
+		}
+		header = header[:i] + header[i+j+2:]
+	}
+	for _, attr := range []string{"__attribute__", "__declspec", "alignas"} {
+		for {
+			i := strings.Index(header, attr)
+			if i < 0 {
+				break
+			}
+			j := i + len(attr)
+			for j < len(header) && header[j] == ' ' {
+				j++
+			}
+			if j < len(header) && header[j] == '(' {
+				depth := 0
+				for ; j < len(header); j++ {
+					if header[j] == '(' {
+						depth++
+					} else if header[j] == ')' {
+						if depth--; depth == 0 {
+							j++
+							break
+						}
+					}
+				}
+			}
+			header = header[:i] + header[j:]
+		}
+	}
+	return strings.Join(strings.Fields(header), " ")
+}
+
+// cStripTemplate drops the template<...> prefixes of a header.
+func cStripTemplate(header string) string {
+	for strings.HasPrefix(header, "template") {
+		rest := strings.TrimSpace(strings.TrimPrefix(header, "template"))
+		if !strings.HasPrefix(rest, "<") {
+			break
+		}
+		end := matchingAngle(rest)
+		if end < 0 {
+			break
+		}
+		header = strings.TrimSpace(rest[end+1:])
+	}
+	return header
+}
+
+// stripTemplateArgs removes the template arguments of a qualified name:
+// "ns::Foo<T>" gives "ns::Foo".
+func stripTemplateArgs(name string) string {
+	for {
+		i := strings.IndexByte(name, '<')
+		if i < 0 {
+			return name
+		}
+		end := matchingAngle(name[i:])
+		if end < 0 {
+			return name[:i]
+		}
+		name = name[:i] + name[i+end+1:]
+	}
+}
+
+// matchingAngle returns the index of the ">" closing the "<" s starts with,
+// or -1.
+func matchingAngle(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			depth++
+		case '>':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// cStripComments returns text with its comments blanked but its string
+// literals kept, using code (text as lexed) to find them. inComment tells
+// whether the line starts inside a block comment.
+func cStripComments(text, code string, inComment bool) string {
+	out := []byte(text)
+	i := 0
+	if inComment {
+		end := strings.Index(text, "*/")
+		if end < 0 {
+			end = len(text) - 2
+		}
+		for ; i < end+2; i++ {
+			out[i] = ' '
+		}
+	}
+	for ; i < len(text); i++ {
+		if code[i] != ' ' {
+			continue
+		}
+		switch {
+		case text[i] == '"' || text[i] == '\'':
+			end, _ := stringEnd(text, i)
+			i = end - 1
+		case strings.HasPrefix(text[i:], "//"):
+			for ; i < len(text); i++ {
+				out[i] = ' '
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			stop := len(text)
+			if end := strings.Index(text[i+2:], "*/"); end >= 0 {
+				stop = i + 2 + end + 2
+			}
+			for ; i < stop; i++ {
+				out[i] = ' '
+			}
+			i--
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,41 @@
+package mapper
+
+import "testing"
+
+func TestCParser(t *testing.T) {
+	runParserTests(t, []parserTest{
+		{"CPlusPlus", ".cpp", `#include <vector>
+
+namespace geo {
+class Shape {
+public:
+    double area() const;
+};
+
+double Shape::area() const {
+    return 0;
+}
+}
+`, []string{
+			"1-1 🔗 depends on: vector",
+			"3-12 🗂️ geo",
+			"4-7 📦 Shape",
+			"6-6 ƒ Shape.area",
+			"9-11 ƒ Shape.area",
+		}},
+		{"CHeader", ".h", `#ifndef S_H
+#define S_H
+
+typedef struct {
+    int x;
+} Point;
+
+int distance(Point a, Point b);
+
+#endif
+`, []string{
+			"4-6 📦 Point",
+			"8-8 ƒ distance",
+		}},
+	})
+}
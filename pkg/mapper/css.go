@@ -0,0 +1,37 @@
+package mapper
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	cssRe    = regexp.MustCompile(`^([^{]+)\{\s*$`)
+	depCssRe = regexp.MustCompile(`@import\s*(?:url\s*\(\s*)?["']?([^"'\)]+)["']?`)
+)
+
+// cssParser maps CSS stylesheets: one region per rule block.
+var cssParser = &lineParser{
+	style:   scopeBraces,
+	syntax:  cssSyntax,
+	detect:  detectCSS,
+	depends: firstGroup(depCssRe),
+}
+
+// scssParser maps SCSS and Less, which add "//" comments to CSS.
+var scssParser = &lineParser{
+	style:   scopeBraces,
+	syntax:  scssSyntax,
+	detect:  detectCSS,
+	depends: firstGroup(depCssRe),
+}
+
+func detectCSS(text string) (lineMatch, bool) {
+	if !strings.Contains(text, "{") {
+		return lineMatch{}, false
+	}
+	if m := cssRe.FindStringSubmatch(strings.TrimSpace(text)); len(m) > 1 {
+		return lineMatch{Kind: KindStyle, Name: strings.TrimSpace(m[1])}, true
+	}
+	return lineMatch{}, false
+}
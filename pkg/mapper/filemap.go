@@ -0,0 +1,247 @@
+package mapper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+const (
+	TextMapSuffix = ".map.txt"
+	JSONMapSuffix = ".map.json"
+	// IndexFile is the per-folder structured index written in JSON mode
+	IndexFile = "_index.map.json"
+	// DepsFile is the workspace dependency map at the scan root, written
+	// as DepsFile + TextMapSuffix and/or DepsFile + JSONMapSuffix
+	DepsFile = "_deps"
+)
+
+// FileMap is the structured form of a single file's map. It is what
+// .map.json files contain and what the level builders read back.
+type FileMap struct {
+	File         string    `json:"file"`
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	LOC          int       `json:"loc"`
+	Modified     time.Time `json:"modified"`
+	Regions      []Region  `json:"regions"`
+	Dependencies []string  `json:"dependencies,omitempty"`
+}
+
+// FolderIndex is the per-folder structured index (_index.map.json).
+type FolderIndex struct {
+	Dir       string    `json:"dir"`
+	Generated time.Time `json:"generated"`
+	Files     []FileMap `json:"files"`
+	Subdirs   []string  `json:"subdirs,omitempty"`
+}
+
+// newFileMap assembles the structured map for a parsed file.
+func newFileMap(path string, info os.FileInfo, loc int, regions []Region) *FileMap {
+	fm := &FileMap{
+		File:     filepath.Base(path),
+		Path:     path,
+		Size:     info.Size(),
+		LOC:      loc,
+		Modified: info.ModTime(),
+		Regions:  make([]Region, 0, len(regions)),
+	}
+	for _, r := range regions {
+		if r.Kind == "" {
+			// A parser that only names regions, like "ƒ main"
+			r = parseLabel(r.Start, r.End, r.Name)
+		}
+		r.Doc = firstSentence(r.Doc)
+		fm.Regions = append(fm.Regions, r)
+	}
+	fm.Dependencies = collectDeps(fm.Regions)
+	return fm
+}
+
+// stripDetail drops signatures and docs, which maps only carry when
+// config.Detail is set.
+func (fm *FileMap) stripDetail() {
+	for i := range fm.Regions {
+		fm.Regions[i].Signature, fm.Regions[i].Doc = "", ""
+	}
+}
+
+// collectDeps lists the distinct dependency names in declaration order.
+func collectDeps(regions []Region) []string {
+	var deps []string
+	seen := make(map[string]bool)
+	for _, r := range regions {
+		if r.Kind == KindImport && r.Name != "" && !seen[r.Name] {
+			seen[r.Name] = true
+			deps = append(deps, r.Name)
+		}
+	}
+	return deps
+}
+
+// Text renders the map in the pipe-delimited .map.txt format.
+func (fm *FileMap) Text() string {
+	var sb strings.Builder
+
+	// --- METADATA HEADER ---
+	sb.WriteString(fmt.Sprintf("File: %s\n", fm.File))
+	sb.WriteString(fmt.Sprintf("Path: %s\n", fm.Path))
+	sb.WriteString(fmt.Sprintf("Size: %.2f KB\n", float64(fm.Size)/1024.0))
+	sb.WriteString(fmt.Sprintf("LOC: %d\n", fm.LOC))
+	sb.WriteString(fmt.Sprintf("Modified: %s\n", fm.Modified.Format("2006-01-02 15:04:05")))
+	sb.WriteString("--------------------------------------------------\n")
+	fm.writeRegions(&sb, "")
+	return sb.String()
+}
+
+// writeRegions writes one "| Start | End | Label" row per region, or a
+// single "(Entire File)" row when the file has no structure. Signatures and
+// docs follow their region on rows without line numbers.
+func (fm *FileMap) writeRegions(sb *strings.Builder, indent string) {
+	if len(fm.Regions) == 0 {
+		sb.WriteString(fmt.Sprintf("%s| %4d | %4d | (Entire File)\n", indent, 1, fm.LOC))
+		return
+	}
+	for _, r := range fm.Regions {
+		sb.WriteString(fmt.Sprintf("%s| %4d | %4d | %s\n", indent, r.Start, r.End, r.Label()))
+		if r.Signature != "" {
+			sb.WriteString(fmt.Sprintf("%s| %4s | %4s | %s%s\n", indent, "", "", detailIndent, r.Signature))
+		}
+		if r.Doc != "" {
+			sb.WriteString(fmt.Sprintf("%s| %4s | %4s | %s%s%s\n", indent, "", "", detailIndent, docPrefix, r.Doc))
+		}
+	}
+}
+
+// Detail rows of text maps: "|      |      |   signature" and "...   // doc"
+const (
+	detailIndent = "  "
+	docPrefix    = "// "
+)
+
+// parseTextMap reads a .map.txt file back into a FileMap.
+func parseTextMap(content string) *FileMap {
+	fm := &FileMap{}
+	inHeader := true
+	for _, l := range strings.Split(content, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if inHeader {
+			key, val, _ := strings.Cut(l, ":")
+			val = strings.TrimSpace(val)
+			switch key {
+			case "File":
+				fm.File = val
+				continue
+			case "Path":
+				fm.Path = val
+				continue
+			case "Size":
+				kb, _ := strconv.ParseFloat(strings.TrimSuffix(val, " KB"), 64)
+				fm.Size = int64(kb * 1024)
+				continue
+			case "LOC":
+				fm.LOC, _ = strconv.Atoi(val)
+				continue
+			case "Modified":
+				fm.Modified, _ = time.ParseInLocation("2006-01-02 15:04:05", val, time.Local)
+				continue
+			}
+			if strings.HasPrefix(l, "-----") {
+				inHeader = false
+				continue
+			}
+		}
+
+		// | Start | End | Label
+		if !strings.HasPrefix(l, "|") {
+			continue
+		}
+		parts := strings.SplitN(l[1:], "|", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		label := strings.TrimSpace(parts[2])
+		if strings.TrimSpace(parts[0]) == "" && len(fm.Regions) > 0 {
+			// Detail row of the previous region
+			r := &fm.Regions[len(fm.Regions)-1]
+			if doc, ok := strings.CutPrefix(label, docPrefix); ok {
+				r.Doc = doc
+			} else {
+				r.Signature = label
+			}
+			continue
+		}
+		start, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+		end, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err1 != nil || err2 != nil || label == "(Entire File)" {
+			continue
+		}
+		fm.Regions = append(fm.Regions, parseLabel(start, end, label))
+	}
+	fm.Dependencies = collectDeps(fm.Regions)
+	return fm
+}
+
+// LoadFileMap reads the map of the source file at path. It prefers the
+// format cfg writes and falls back to the other one.
+func LoadFileMap(cfg config.Config, path string) (*FileMap, error) {
+	base := outputPath(cfg, path)
+	loadJSON := func() (*FileMap, error) {
+		data, err := os.ReadFile(base + JSONMapSuffix)
+		if err != nil {
+			return nil, err
+		}
+		var fm FileMap
+		if err := json.Unmarshal(data, &fm); err != nil {
+			return nil, fmt.Errorf("%s: %w", base+JSONMapSuffix, err)
+		}
+		return &fm, nil
+	}
+	loadText := func() (*FileMap, error) {
+		data, err := os.ReadFile(base + TextMapSuffix)
+		if err != nil {
+			return nil, err
+		}
+		return parseTextMap(string(data)), nil
+	}
+
+	first, second := loadText, loadJSON
+	if !cfg.WritesText() {
+		first, second = loadJSON, loadText
+	}
+	fm, err := first()
+	if errors.Is(err, os.ErrNotExist) {
+		return second()
+	}
+	return fm, err
+}
+
+// IsMapFile reports whether name is a file written by astrmap itself.
+func IsMapFile(name string) bool {
+	return strings.HasSuffix(name, TextMapSuffix) || strings.HasSuffix(name, JSONMapSuffix)
+}
+
+// isFolderMap reports whether name is one of the per-folder maps
+// (_level_N.map.txt, _index.map.json or _deps.map.*) rather than a file map.
+func isFolderMap(name string) bool {
+	return name == IndexFile || name == DepsFile+TextMapSuffix || name == DepsFile+JSONMapSuffix ||
+		(strings.HasPrefix(name, "_level_") && strings.HasSuffix(name, TextMapSuffix))
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
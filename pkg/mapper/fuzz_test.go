@@ -0,0 +1,90 @@
+package mapper
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fuzzParser checks that the parser registered for ext never panics and
+// returns regions within the lines of its input.
+func fuzzParser(f *testing.F, ext string, seeds ...string) {
+	p := ParserFor(ext)
+	if p == nil {
+		f.Fatalf("no parser for %s", ext)
+	}
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, src []byte) {
+		lines := bytes.Count(src, []byte("\n")) + 1
+		for _, r := range p.Parse(src) {
+			if r.Start < 1 || r.End < r.Start || r.End > lines {
+				t.Fatalf("region %+v outside lines 1-%d", r, lines)
+			}
+		}
+	})
+}
+
+func FuzzGo(f *testing.F) {
+	fuzzParser(f, ".go", "package a\n\nfunc F[T any](x T) {}\n", "type S struct{\n\tA int\n}\n", "func (", "\"")
+}
+
+func FuzzJavaScript(f *testing.F) {
+	fuzzParser(f, ".js", "export function f() {\n}\n", "describe('a', () => {\n  it('b', () => {})\n})\n", "const s = `${", "/")
+}
+
+func FuzzTypeScript(f *testing.F) {
+	fuzzParser(f, ".ts", "export type A =\n  | 'a'\n", "function f(a: string): void;\nfunction f(a: any) {\n}\n", "@Dec(\nclass A {}", "enum E {")
+}
+
+func FuzzJava(f *testing.F) {
+	fuzzParser(f, ".java", "public class A {\n  void f() {}\n}\n", "String s = \"\"\"\n{\n\"\"\";\n")
+}
+
+func FuzzCSharp(f *testing.F) {
+	fuzzParser(f, ".cs", "public class A {\n  public void F() {}\n}\n", "var s = @\"\n\"\"{\";\n")
+}
+
+func FuzzCSS(f *testing.F) {
+	fuzzParser(f, ".css", "a {\n  color: red;\n}\n", "@import url(x.css);\n", "/*")
+}
+
+func FuzzSCSS(f *testing.F) {
+	fuzzParser(f, ".scss", ".a {\n  .b {\n  }\n}\n", "// {\n")
+}
+
+func FuzzPython(f *testing.F) {
+	fuzzParser(f, ".py", "def f():\n    return \"\n", "class A:\n    @d\n    async def m(self):\n        '''doc'''\n", "x = (\n", "\\")
+}
+
+func FuzzRust(f *testing.F) {
+	fuzzParser(f, ".rs", "impl<T> A<T> {\n    fn f(&self) {}\n}\n", "let s = r#\"\n{\"#;\n", "'a")
+}
+
+func FuzzC(f *testing.F) {
+	fuzzParser(f, ".cpp", "namespace a {\nint f(int x) {\n  return x;\n}\n}\n", "#if 0\n#endif\n", "TEST(A, B) {\n}\n", "#define F(x) \\\n")
+}
+
+func FuzzCHeader(f *testing.F) {
+	fuzzParser(f, ".h", "#ifndef A_H\n#define A_H\nint f(void);\n#endif\n", "typedef struct {\n  int a;\n} T;\n")
+}
+
+func FuzzHTML(f *testing.F) {
+	fuzzParser(f, ".html", "<body>\n<div id=\"a\"><p>x</p>\n</div>\n</body>\n", "<script src=\"a.js\"></script>\n")
+}
+
+func FuzzVue(f *testing.F) {
+	fuzzParser(f, ".vue", "<template>\n  <div></div>\n</template>\n<script setup lang=\"ts\">\ndefineProps<{ a: string }>()\n</script>\n", "<script>\nexport default {\n  props: [\n", "<style")
+}
+
+func FuzzSvelte(f *testing.F) {
+	fuzzParser(f, ".svelte", "<script>\n  export let a;\n  dispatch(\"x\")\n</script>\n", "<script lang=\"ts\">\nlet { a } = $props()\n")
+}
+
+func FuzzAstro(f *testing.F) {
+	fuzzParser(f, ".astro", "---\ninterface Props {\n  a: string\n}\n---\n<main></main>\n", "---\n")
+}
+
+func FuzzMarkdown(f *testing.F) {
+	fuzzParser(f, ".md", "# A\ntext\n## B\n", "#")
+}
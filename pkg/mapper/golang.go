@@ -0,0 +1,229 @@
+package mapper
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// parseGo maps a Go source file from its AST. Every top-level declaration
+// becomes a region with exact start/end lines, including unexported funcs,
+// generic types and the specs of grouped type/const/var blocks.
+func parseGo(src []byte) []Region {
+	fset := token.NewFileSet()
+	// ParseFile returns a partial AST on syntax errors; map whatever it found.
+	file, _ := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+	text := func(from, to token.Pos) string {
+		// The partial AST of a half-typed file may hold missing positions
+		start, end := fset.Position(from).Offset, fset.Position(to).Offset
+		if !from.IsValid() || !to.IsValid() || start > end || end > len(src) {
+			return ""
+		}
+		return string(src[start:end])
+	}
+	var regions []Region
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			r := Region{
+				Start: line(d.Pos()), End: line(d.End()),
+				Kind: KindFunction, Name: d.Name.Name, Form: goTypeParams(d.Type.TypeParams),
+				Exported: d.Name.IsExported(), Doc: strings.TrimSpace(d.Doc.Text()),
+			}
+			if recv := goReceiverName(d); recv != "" {
+				r.Kind, r.Parent = KindMethod, recv
+			}
+			if d.Body != nil {
+				r.Signature = signature(text(d.Pos(), d.Body.Lbrace))
+			} else {
+				r.Signature = signature(text(d.Pos(), d.End()))
+			}
+			regions = append(regions, r)
+
+		case *ast.GenDecl:
+			grouped := d.Lparen.IsValid()
+			switch d.Tok {
+			case token.IMPORT:
+				if grouped {
+					regions = append(regions, Region{Start: line(d.Pos()), End: line(d.End()), Kind: KindImport})
+				}
+				for _, spec := range d.Specs {
+					is := spec.(*ast.ImportSpec)
+					dep := strings.Trim(is.Path.Value, "\"`")
+					l := line(is.Path.Pos())
+					regions = append(regions, Region{Start: l, End: l, Kind: KindImport, Name: dep})
+				}
+
+			case token.TYPE:
+				for _, spec := range d.Specs {
+					ts := spec.(*ast.TypeSpec)
+					r := Region{
+						Start: line(ts.Pos()), End: line(ts.End()),
+						Kind: goTypeKind(ts), Name: ts.Name.Name,
+						Form:     goTypeParams(ts.TypeParams) + " (" + goTypeForm(ts) + ")",
+						Exported: ts.Name.IsExported(), Doc: strings.TrimSpace(ts.Doc.Text()),
+					}
+					if !grouped {
+						r.Start, r.Doc = line(d.Pos()), strings.TrimSpace(d.Doc.Text())
+					}
+					// Struct and interface bodies are not part of the signature
+					sig := "type " + text(ts.Pos(), ts.End())
+					if r.Kind != KindType {
+						sig, _, _ = strings.Cut(sig, "{")
+					}
+					r.Signature = signature(sig)
+					regions = append(regions, r)
+				}
+
+			case token.CONST, token.VAR:
+				kind, label := KindConst, "Const"
+				if d.Tok == token.VAR {
+					kind, label = KindVar, "Var"
+				}
+				r := Region{
+					Start: line(d.Pos()), End: line(d.End()), Kind: kind,
+					Doc: strings.TrimSpace(d.Doc.Text()),
+				}
+				if grouped {
+					r.Name = label
+					for _, spec := range d.Specs {
+						for _, n := range spec.(*ast.ValueSpec).Names {
+							r.Exported = r.Exported || n.IsExported()
+						}
+					}
+					regions = append(regions, r)
+					continue
+				}
+				for _, spec := range d.Specs {
+					vs := spec.(*ast.ValueSpec)
+					var names []string
+					for _, n := range vs.Names {
+						names = append(names, n.Name)
+						r.Exported = r.Exported || n.IsExported()
+					}
+					r.Name = strings.Join(names, ", ")
+					sig, _, _ := strings.Cut(text(d.Pos(), d.End()), "\n")
+					r.Signature = signature(sig)
+					regions = append(regions, r)
+				}
+			}
+		}
+	}
+
+	// A declaration cut short by a syntax error has no end
+	for i := range regions {
+		regions[i].End = max(regions[i].End, regions[i].Start)
+	}
+
+	// Manual markers, taken from real comments only (never from string literals)
+	lines := bytes.Split(src, []byte("\n"))
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, "//") {
+				continue
+			}
+			l := line(c.Pos())
+			if l < 1 || l > len(lines) {
+				continue
+			}
+			if name, ok := markerName(string(lines[l-1])); ok && name != "" {
+				regions = append(regions, Region{Start: l, End: l, Kind: KindMarker, Name: name})
+			}
+		}
+	}
+
+	return regions
+}
+
+// goReceiverName returns the bare receiver type of a method ("Server" for
+// both "(s Server)" and "(s *Server[T])"), or "" for plain functions.
+func goReceiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return types.ExprString(expr)
+		}
+	}
+}
+
+// goTypeKind returns the region kind of a type declaration: structs are
+// classes, interfaces interfaces, and everything else a plain type.
+func goTypeKind(ts *ast.TypeSpec) Kind {
+	if ts.Assign.IsValid() {
+		return KindType
+	}
+	switch ts.Type.(type) {
+	case *ast.StructType:
+		return KindClass
+	case *ast.InterfaceType:
+		return KindInterface
+	}
+	return KindType
+}
+
+// goTypeParams renders a type parameter list as "[K comparable, V any]".
+func goTypeParams(fl *ast.FieldList) string {
+	if fl == nil || len(fl.List) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, f := range fl.List {
+		var names []string
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		parts = append(parts, strings.Join(names, ", ")+" "+types.ExprString(f.Type))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// goTypeForm describes the underlying form of a type declaration.
+func goTypeForm(ts *ast.TypeSpec) string {
+	if ts.Assign.IsValid() {
+		return "alias of " + types.ExprString(ts.Type)
+	}
+	switch t := ts.Type.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	case *ast.FuncType:
+		return "func"
+	case *ast.MapType:
+		return "map"
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "slice"
+		}
+		return "array"
+	case *ast.ChanType:
+		return "chan"
+	case *ast.StarExpr:
+		return "pointer"
+	default:
+		return types.ExprString(ts.Type)
+	}
+}
@@ -0,0 +1,33 @@
+package mapper
+
+import "testing"
+
+func TestGoParser(t *testing.T) {
+	runParserTests(t, []parserTest{
+		{"Go", ".go", `package a
+
+import (
+	"fmt"
+)
+
+// Server serves.
+type Server[T any] struct {
+	n int
+}
+
+func (s *Server[T]) Handle() {
+	fmt.Println(s.n)
+}
+
+const (
+	A = 1
+)
+`, []string{
+			"3-5 📥 Imports",
+			"4-4 🔗 depends on: fmt",
+			"8-10 📦 Server[T any] (struct)",
+			"12-14 ƒ Server.Handle",
+			"16-18 🧱 Const",
+		}},
+	})
+}
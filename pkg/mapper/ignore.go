@@ -0,0 +1,79 @@
+package mapper
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+	"github.com/hubby247/astrmap/pkg/fs"
+)
+
+// Ignores decides which paths below a root are left out of maps: the
+// default and configured ignored folder names, the output directory, and
+// the rules of .gitignore and .astrmapignore files. Every walk over a source
+// tree uses one, so they all agree on what is mapped.
+type Ignores struct {
+	cfg   config.Config
+	root  string
+	names []string // RootConfig.IgnoredDirs of the root
+	files *fs.Ignorer
+}
+
+// NewIgnores returns the ignores of the root containing dir.
+func NewIgnores(cfg config.Config, dir string) *Ignores {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	root := findRoot(cfg, abs)
+	if rel, err := filepath.Rel(root, abs); err != nil || strings.HasPrefix(rel, "..") {
+		// Not below any root: the directory is its own root
+		root = abs
+	}
+	return &Ignores{
+		cfg:   cfg,
+		root:  root,
+		names: ignoredDirsFor(cfg, root),
+		files: fs.NewIgnorer(root),
+	}
+}
+
+// Skip reports whether a walk should leave out path. Parent directories are
+// not checked: the walk already skipped excluded ones.
+func (ig *Ignores) Skip(path string, isDir bool) bool {
+	if isDir && (fs.ShouldIgnoreName(filepath.Base(path), ig.names) || isOutputDir(ig.cfg, path)) {
+		return true
+	}
+	return ig.files.Match(path, isDir)
+}
+
+// Ignored reports whether path, or any directory between the root and path,
+// is left out of maps.
+func (ig *Ignores) Ignored(path string, isDir bool) bool {
+	for dir := filepath.Dir(path); len(dir) > len(ig.root); dir = filepath.Dir(dir) {
+		if fs.ShouldIgnoreName(filepath.Base(dir), ig.names) || isOutputDir(ig.cfg, dir) {
+			return true
+		}
+	}
+	if isDir && (fs.ShouldIgnoreName(filepath.Base(path), ig.names) || isOutputDir(ig.cfg, path)) {
+		return true
+	}
+	return ig.files.Ignored(path, isDir)
+}
+
+// Reset rereads the ignore files on the next check.
+func (ig *Ignores) Reset() {
+	ig.files.Reset()
+}
+
+// IsIgnoreFile reports whether path is a .gitignore or .astrmapignore file,
+// whose changes change what is mapped.
+func IsIgnoreFile(path string) bool {
+	base := filepath.Base(path)
+	for _, name := range fs.IgnoreFiles {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
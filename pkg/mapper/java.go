@@ -0,0 +1,55 @@
+package mapper
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	javaClassRe  = regexp.MustCompile(`^\s*(?:public|protected|private)?\s*(?:static\s+)?(?:final\s+)?class\s+([a-zA-Z0-9_]+)`)
+	javaMethodRe = regexp.MustCompile(`^\s*(?:public|protected|private)\s+(?:static\s+)?(?:final\s+)?[\w<>\[\]]+\s+([a-zA-Z0-9_]+)\s*\(`)
+	// javaAnnotationRe matches a line of annotations only, the last one
+	// possibly continued on the next lines: @Override, @GetMapping("/a")
+	javaAnnotationRe = regexp.MustCompile(`^(?:@[\w.]+\s*(?:\([^)]*\))?\s*)*@[\w.]+\s*(?:\([^)]*\)?)?\s*$`)
+	// csharpAttributeRe matches a line of attributes only, the last one
+	// possibly continued on the next lines: [Obsolete], [return: NotNull]
+	csharpAttributeRe = regexp.MustCompile(`^(?:\[\s*(?:\w+\s*:\s*)?[A-Za-z_][^\[\]]*\]\s*)*(?:\[\s*(?:\w+\s*:\s*)?[A-Za-z_][^\[\]]*\]|\[\s*[A-Za-z_][^\[\]]*\()\s*$`)
+)
+
+// javaParser maps Java sources.
+var javaParser = &lineParser{
+	style:     scopeBraces,
+	syntax:    javaSyntax,
+	detect:    detectJava,
+	attribute: javaAnnotation,
+}
+
+// csharpParser maps C# sources, whose declarations look like Java's.
+var csharpParser = &lineParser{
+	style:     scopeBraces,
+	syntax:    csharpSyntax,
+	detect:    detectJava,
+	attribute: csharpAttribute,
+}
+
+func detectJava(text string) (lineMatch, bool) {
+	public := strings.HasPrefix(strings.TrimSpace(text), "public")
+	if m := javaClassRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindClass, Name: m[1], Exported: public}, true
+	} else if m := javaMethodRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindFunction, Name: m[1], Exported: public}, true
+	}
+	return lineMatch{}, false
+}
+
+// javaAnnotation recognizes annotation lines, which may stand between a
+// Javadoc comment and the class or member they annotate.
+func javaAnnotation(trimmed string) (ok, test bool) {
+	return javaAnnotationRe.MatchString(trimmed) && !strings.HasPrefix(trimmed, "@interface"), false
+}
+
+// csharpAttribute recognizes attribute lines, which may stand between an
+// XML doc comment and the type or member they apply to.
+func csharpAttribute(trimmed string) (ok, test bool) {
+	return csharpAttributeRe.MatchString(trimmed), false
+}
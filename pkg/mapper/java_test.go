@@ -0,0 +1,36 @@
+package mapper
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestJavaAnnotatedDoc(t *testing.T) {
+	tests := []struct {
+		name, ext, src string
+		region, want   string
+	}{
+		{"Java annotation", ".java", "public class Task {\n    /** Runs the task. */\n    @Override\n    public void run() {\n    }\n}\n", "run", "Runs the task."},
+		{"Java multi-line annotation", ".java", "public class Api {\n    /**\n     * Lists the users.\n     */\n    @GetMapping(\n        \"/users\")\n    @ResponseBody\n    public List<User> users() {\n    }\n}\n", "users", "Lists the users."},
+		{"C# attribute", ".cs", "public class Greeter {\n    /// <summary>Says hi.</summary>\n    [Obsolete(\"use Hello\")]\n    public void Hi() {\n    }\n}\n", "Hi", "Says hi."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "Sample"+tt.ext)
+			writeFile(t, path, tt.src)
+			fm, err := BuildFileMap(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range fm.Regions {
+				if r.Name == tt.region {
+					if r.Doc != tt.want {
+						t.Errorf("doc of %s = %q, want %q", tt.region, r.Doc, tt.want)
+					}
+					return
+				}
+			}
+			t.Errorf("no region %s in %v", tt.region, fm.Regions)
+		})
+	}
+}
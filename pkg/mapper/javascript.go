@@ -0,0 +1,159 @@
+package mapper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// jsMods are the modifiers TypeScript allows before a class member.
+const jsMods = `(?:(?:public|private|protected|static|readonly|abstract|override|async|declare|accessor)\s+)*`
+
+var (
+	// JS/TS
+	jsFuncRe      = regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:async\s+)?function\s*\*?\s*([\w$]+)\s*(?:<[^(]*>)?\s*\(`)
+	jsClassRe     = regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?class\s+([\w$]+)`)
+	jsArrowRe     = regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([\w$]+)\s*(?::[^=]+)?=\s*(?:async\s*)?(?:<[^(]*>\s*)?(?:\([^)]*\)|[\w$]+)\s*(?::[^=]+)?=>`)
+	tsInterfaceRe = regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:declare\s+)?interface\s+([\w$]+)`)
+	tsTypeRe      = regexp.MustCompile(`^(?:export\s+)?(?:declare\s+)?type\s+([\w$]+)\s*(?:<.*>)?\s*=`)
+	tsEnumRe      = regexp.MustCompile(`^(?:export\s+)?(?:declare\s+)?(?:const\s+)?enum\s+([\w$]+)`)
+	// namespace A.B {, declare module "pkg" {, declare global {
+	tsNamespaceRe = regexp.MustCompile(`^(?:export\s+)?(?:declare\s+)?(?:(?:namespace|module)\s+([\w$.]+|"[^"]+"|'[^']+')|(global))\s*\{`)
+	// Where a top-level statement starts, ending a type alias without a semicolon
+	jsStatementRe = regexp.MustCompile(`^(?:export|import|type|interface|function|const|let|var|class|enum|declare|namespace|abstract|async)\b`)
+
+	// JS Testing & Objects
+	jsDescribeRe      = regexp.MustCompile(`^\s*(?:describe|context|suite)\s*\(\s*["']([^"']+)["']`)
+	jsItRe            = regexp.MustCompile(`^\s*(?:it|test)\s*\(\s*["']([^"']+)["']`)
+	jsObjRe           = regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([\w$]+)\s*(?::[^=]+)?=\s*\{`)
+	jsExportDefaultRe = regexp.MustCompile(`^export\s+default\s*(?:defineComponent\s*\(\s*)?\{`)
+	jsRouteRe         = regexp.MustCompile(`^(?:router|app)\.(get|post|put|delete|patch|use)\s*\(\s*["']([^"']+)["']`)
+	jsDecoratorRe     = regexp.MustCompile(`^@[\w$.]+`)
+
+	// Members of classes, interfaces and object literals: methods with
+	// modifiers, getters and setters, "foo?(): T" signatures, and functions
+	// as properties or class fields
+	jsMemberRe     = regexp.MustCompile(`^(` + jsMods + `)(?:(?:get|set)\s+)?\*?\s*(#?[\w$]+)\s*[?!]?\s*(?:<[^(]*>)?\s*\(`)
+	jsFieldArrowRe = regexp.MustCompile(`^(` + jsMods + `)(#?[\w$]+)\s*[?!]?\s*(?::[^=]+)?=\s*(?:async\s*)?(?:\([^)]*\)|[\w$]+)\s*(?::[^=]+)?=>`)
+	jsPropFuncRe   = regexp.MustCompile(`^\s*([\w$]+)\s*:\s*(?:async\s+)?function\s*\*?\s*\(`)
+	jsPropArrowRe  = regexp.MustCompile(`^\s*([\w$]+)\s*:\s*(?:async\s+)?(?:\([^)]*\)|[\w$]+)\s*=>`)
+
+	// Words that look like a member before "(" but start a statement
+	jsKeywords = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true,
+		"catch": true, "function": true, "return": true, "await": true, "else": true,
+		"new": true, "typeof": true, "super": true, "this": true, "with": true,
+	}
+
+	// import x from "m", import "m", export * from "m", } from "m" (the end
+	// of a multi-line import) and require("m")
+	depJsRe = regexp.MustCompile(`^\s*(?:import\s+(?:[^"']*\bfrom\s+)?|export\s+[^"']*\bfrom\s+|\}\s*from\s+|(?:(?:const|let|var)\s+[^=]+=\s*)?require\s*\(\s*)["']([^"']+)["']`)
+)
+
+// javascriptParser maps JavaScript and TypeScript sources. Members are
+// only looked for inside classes, interfaces, object literals and
+// namespaces, so that calls in function bodies are not taken for methods.
+var javascriptParser = &lineParser{
+	style:     scopeBraces,
+	syntax:    jsSyntax,
+	attribute: jsAttribute,
+	detect:    detectJS,
+	member:    memberJS,
+	depends:   firstGroup(depJsRe),
+	overloads: true,
+}
+
+func detectJS(text string) (lineMatch, bool) {
+	exported := strings.HasPrefix(text, "export") || strings.HasPrefix(text, "declare")
+	if m := jsFuncRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindFunction, Name: m[1], Exported: exported}, true
+	} else if m := jsClassRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindClass, Name: m[1], Exported: exported}, true
+	} else if m := jsArrowRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindFunction, Name: m[1], Exported: exported}, true
+	} else if m := tsInterfaceRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindInterface, Name: m[1], Exported: exported}, true
+	} else if m := tsTypeRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindType, Name: m[1], Exported: exported, CloseChar: ";"}, true
+	} else if m := tsEnumRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindType, Name: m[1], Exported: exported}, true
+	} else if m := tsNamespaceRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindModule, Name: strings.Trim(m[1]+m[2], `"'`), Exported: exported}, true
+	} else if m := jsDescribeRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindSuite, Name: m[1], CloseChar: ")"}, true
+	} else if m := jsItRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindTest, Name: m[1], CloseChar: ")"}, true
+	} else if m := jsObjRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindConst, Name: m[1], Exported: exported}, true
+	} else if jsExportDefaultRe.MatchString(text) {
+		return lineMatch{Kind: KindClass, Name: "default export", Exported: true}, true
+	} else if m := jsRouteRe.FindStringSubmatch(text); len(m) > 2 {
+		method := strings.ToUpper(m[1])
+		path := m[2]
+		return lineMatch{Kind: KindRoute, Name: method + " " + path, CloseChar: ")"}, true
+	}
+	return lineMatch{}, false
+}
+
+// memberJS detects the lines inside a region. Classes, interfaces and
+// objects hold members; namespaces hold declarations like a file does.
+func memberJS(text string, parent Kind, direct bool) (lineMatch, bool) {
+	switch parent {
+	case KindClass, KindInterface, KindConst:
+		return jsMemberLine(strings.TrimSpace(text), parent, direct)
+	case KindModule:
+		if direct {
+			return detectJS(strings.TrimSpace(text))
+		}
+		return lineMatch{}, false
+	}
+	// Function bodies and suites: nested suites and tests
+	return detectJS(text)
+}
+
+// jsMemberLine detects a member of a class, interface or object literal.
+// A signature without a body, like an overload, an abstract method or an
+// interface method, is only a member directly in the body; deeper, it
+// would be a call.
+func jsMemberLine(trimmed string, parent Kind, direct bool) (lineMatch, bool) {
+	if m := jsMemberRe.FindStringSubmatch(trimmed); m != nil && !jsKeywords[m[2]] {
+		body := strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, "(")
+		if parent == KindInterface && direct || parent != KindInterface && (body || direct && parent == KindClass) {
+			return jsMember(m[2], m[1]), true
+		}
+		return lineMatch{}, false
+	}
+	if parent == KindInterface {
+		return lineMatch{}, false
+	}
+	if m := jsFieldArrowRe.FindStringSubmatch(trimmed); m != nil && direct {
+		return jsMember(m[2], m[1]), true
+	} else if m := jsPropFuncRe.FindStringSubmatch(trimmed); m != nil {
+		return jsMember(m[1], ""), true
+	} else if m := jsPropArrowRe.FindStringSubmatch(trimmed); m != nil {
+		return jsMember(m[1], ""), true
+	}
+	return lineMatch{}, false
+}
+
+// jsAttribute recognizes decorators, which may stand between a doc comment
+// and the class or member they decorate.
+func jsAttribute(trimmed string) (ok, test bool) {
+	return jsDecoratorRe.MatchString(trimmed) && !strings.HasPrefix(trimmed, "@param"), false
+}
+
+// jsMember is a method or function-valued property: public unless a
+// modifier or its name marks it private.
+func jsMember(name, mods string) lineMatch {
+	private := strings.Contains(mods, "private") || strings.Contains(mods, "protected")
+	return lineMatch{Kind: KindFunction, Name: name, Exported: !private && !strings.HasPrefix(name, "_") && !strings.HasPrefix(name, "#")}
+}
+
+// firstGroup returns a dependency extractor yielding the first capture group of re.
+func firstGroup(re *regexp.Regexp) func(string) string {
+	return func(text string) string {
+		if m := re.FindStringSubmatch(text); len(m) > 1 {
+			return m[1]
+		}
+		return ""
+	}
+}
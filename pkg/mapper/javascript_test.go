@@ -0,0 +1,34 @@
+package mapper
+
+import "testing"
+
+func TestTypeScriptParser(t *testing.T) {
+	runParserTests(t, []parserTest{
+		{"TypeScript", ".ts", `export interface Props {
+  a: string
+}
+
+export type Id = string
+
+export enum Mode {
+  A,
+}
+
+export async function load(id: Id): Promise<void> {
+}
+`, []string{
+			"1-3 📄 Props",
+			"5-5 🏷️ Id",
+			"7-9 🏷️ Mode",
+			"11-12 ƒ load",
+		}},
+		{"TypeScript namespace", ".ts", `export namespace NS {
+  export function inner() {
+  }
+}
+`, []string{
+			"1-4 🗂️ NS",
+			"2-3 ƒ NS.inner",
+		}},
+	})
+}
@@ -0,0 +1,368 @@
+package mapper
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// braceSyntax lists the comment and literal forms of a brace language, so
+// braces inside them are not counted as nesting.
+type braceSyntax struct {
+	lineComments bool // "// ..." (not plain CSS, where "//" appears in URLs)
+	templates    bool // JS `...${expr}...` template literals
+	regexps      bool // JS /.../ regular expression literals
+	textBlocks   bool // Java and C# """...""" multi-line strings
+	verbatim     bool // C# @"..." multi-line strings, "" escapes a quote
+	rawStrings   bool // Rust r"..." and r#"..."# multi-line strings
+	multiline    bool // "..." strings may span lines (Rust)
+	lifetimes    bool // Rust 'a lifetimes, which are not char literals
+}
+
+var (
+	cssSyntax    = braceSyntax{}
+	scssSyntax   = braceSyntax{lineComments: true}
+	jsSyntax     = braceSyntax{lineComments: true, templates: true, regexps: true}
+	javaSyntax   = braceSyntax{lineComments: true, textBlocks: true}
+	csharpSyntax = braceSyntax{lineComments: true, textBlocks: true, verbatim: true}
+	rustSyntax   = braceSyntax{lineComments: true, rawStrings: true, multiline: true, lifetimes: true}
+	cSyntax      = braceSyntax{lineComments: true}
+)
+
+// lexMode is what a braceLexer is inside of at a line boundary.
+type lexMode int
+
+const (
+	lexCode         lexMode = iota
+	lexBlockComment         // /* ... */
+	lexTemplate             // `...` (JS)
+	lexTextBlock            // """...""" (Java, C#)
+	lexVerbatim             // @"..." (C#)
+	lexRaw                  // r#"..."# (Rust)
+	lexString               // "..." continued from a previous line (Rust)
+)
+
+// braceLexer blanks out comments and literals line by line, carrying block
+// comments and multi-line strings over to the next line.
+type braceLexer struct {
+	syntax braceSyntax
+	mode   lexMode
+	// interp holds, for each open ${...} of a template literal, the braces
+	// opened inside it: its "}" is the one that closes at zero
+	interp []int
+	// prev is the last code character before the current position, to tell
+	// a regexp literal from a division
+	prev     byte
+	prevWord string
+	// rawEnd closes the current raw string: a quote and its hashes
+	rawEnd string
+}
+
+// inCode reports whether the next line starts in code, not in a comment or
+// a multi-line string.
+func (l *braceLexer) inCode() bool {
+	return l.mode == lexCode
+}
+
+// code returns line with every comment and literal character, quotes
+// included, replaced by a space, so only structural braces and parentheses
+// remain. Column positions are unchanged.
+func (l *braceLexer) code(line string) string {
+	out := []byte(line)
+	blank := func(from, to int) {
+		for k := from; k < to && k < len(out); k++ {
+			out[k] = ' '
+		}
+	}
+
+	for i := 0; i < len(line); {
+		switch l.mode {
+		case lexBlockComment:
+			end := strings.Index(line[i:], "*/")
+			if end < 0 {
+				blank(i, len(line))
+				return string(out)
+			}
+			blank(i, i+end+2)
+			i += end + 2
+			l.mode = lexCode
+			continue
+
+		case lexTextBlock:
+			end := strings.Index(line[i:], `"""`)
+			if end < 0 {
+				blank(i, len(line))
+				return string(out)
+			}
+			blank(i, i+end+3)
+			i += end + 3
+			l.mode = lexCode
+			l.prev = '"'
+			continue
+
+		case lexRaw:
+			end := strings.Index(line[i:], l.rawEnd)
+			if end < 0 {
+				blank(i, len(line))
+				return string(out)
+			}
+			blank(i, i+end+len(l.rawEnd))
+			i += end + len(l.rawEnd)
+			l.mode = lexCode
+			l.prev = '"'
+			continue
+
+		case lexString:
+			// Only ever continues at the start of a line
+			end, closed := stringEnd(line, -1)
+			blank(i, end)
+			if !closed {
+				return string(out)
+			}
+			i = end
+			l.mode = lexCode
+			l.prev = '"'
+			continue
+
+		case lexVerbatim:
+			j := i
+			for j < len(line) && !(line[j] == '"' && (j+1 == len(line) || line[j+1] != '"')) {
+				if line[j] == '"' {
+					j++ // "" is an escaped quote
+				}
+				j++
+			}
+			if j >= len(line) {
+				blank(i, len(line))
+				return string(out)
+			}
+			blank(i, j+1)
+			i = j + 1
+			l.mode = lexCode
+			l.prev = '"'
+			continue
+
+		case lexTemplate:
+			j := i
+			for j < len(line) && line[j] != '`' && !(line[j] == '$' && j+1 < len(line) && line[j+1] == '{') {
+				if line[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(line) {
+				blank(i, len(line))
+				return string(out)
+			}
+			if line[j] == '`' {
+				blank(i, j+1)
+				i = j + 1
+				l.mode = lexCode
+				l.prev = '`'
+				continue
+			}
+			// ${ starts an expression: code again until its closing brace
+			blank(i, j+2)
+			i = j + 2
+			l.interp = append(l.interp, 0)
+			l.mode = lexCode
+			l.prev = '{'
+			continue
+		}
+
+		// lexCode
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+			continue
+
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			l.mode = lexBlockComment
+			blank(i, i+2)
+			i += 2
+			continue
+
+		case c == '/' && i+1 < len(line) && line[i+1] == '/' && l.syntax.lineComments:
+			blank(i, len(line))
+			return string(out)
+
+		case c == '/' && l.syntax.regexps && l.regexpAllowed():
+			end := regexpEnd(line, i)
+			blank(i, end)
+			i = end
+			l.prev = '/'
+			continue
+
+		case c == '"' && l.syntax.textBlocks && strings.HasPrefix(line[i:], `"""`):
+			l.mode = lexTextBlock
+			blank(i, i+3)
+			i += 3
+			continue
+
+		case c == '@' && l.syntax.verbatim && i+1 < len(line) && line[i+1] == '"':
+			l.mode = lexVerbatim
+			blank(i, i+2)
+			i += 2
+			continue
+
+		case c == '`' && l.syntax.templates:
+			l.mode = lexTemplate
+			blank(i, i+1)
+			i++
+			continue
+
+		case (c == 'r' || c == 'b') && l.syntax.rawStrings && (i == 0 || !isWordByte(line[i-1])) && rawStart(line, i) > i:
+			j := rawStart(line, i)
+			l.rawEnd = `"` + strings.Repeat("#", strings.Count(line[i:j], "#"))
+			l.mode = lexRaw
+			blank(i, j)
+			i = j
+			continue
+
+		case c == '\'' && l.syntax.lifetimes && !isCharLiteral(line, i):
+			// 'a: a lifetime or label
+			l.prev = c
+			i++
+			continue
+
+		case c == '"' || c == '\'':
+			end, closed := stringEnd(line, i)
+			blank(i, end)
+			if !closed && c == '"' && l.syntax.multiline {
+				l.mode = lexString
+				return string(out)
+			}
+			i = end
+			l.prev = c
+			continue
+
+		case c == '{' && len(l.interp) > 0:
+			l.interp[len(l.interp)-1]++
+
+		case c == '}' && len(l.interp) > 0:
+			top := len(l.interp) - 1
+			if l.interp[top] == 0 {
+				// End of a ${...} expression: back inside the template
+				l.interp = l.interp[:top]
+				out[i] = ' '
+				i++
+				l.mode = lexTemplate
+				continue
+			}
+			l.interp[top]--
+		}
+
+		if isWordByte(c) {
+			j := i
+			for j < len(line) && isWordByte(line[j]) {
+				j++
+			}
+			l.prevWord = line[i:j]
+			l.prev = line[j-1]
+			i = j
+			continue
+		}
+		l.prevWord = ""
+		l.prev = c
+		i++
+	}
+
+	// Quoted strings never continue on the next line
+	return string(out)
+}
+
+// regexpAllowed reports whether a "/" at the current position starts a
+// regexp literal rather than a division: it must follow an operator, an
+// opening bracket or a keyword such as return.
+func (l *braceLexer) regexpAllowed() bool {
+	switch l.prevWord {
+	case "":
+	case "return", "typeof", "case", "do", "else", "in", "of", "new", "delete", "void", "throw", "yield", "await":
+		return true
+	default:
+		return false
+	}
+	return l.prev == 0 || strings.IndexByte("(,=:[!&|?{};+-*%<>~^", l.prev) >= 0
+}
+
+// stringEnd returns the index just past the string literal whose quote is
+// line[start], or the end of line when it is not closed there. A start of
+// -1 continues a "..." string from the previous line.
+func stringEnd(line string, start int) (end int, closed bool) {
+	quote := byte('"')
+	if start >= 0 {
+		quote = line[start]
+	}
+	for j := start + 1; j < len(line); j++ {
+		switch line[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1, true
+		}
+	}
+	return len(line), false
+}
+
+// rawStart returns the index just past the opening quote of the Rust raw
+// string (r"", r#""#, br"") starting at line[i], or i when there is none.
+func rawStart(line string, i int) int {
+	j := i
+	if line[j] == 'b' {
+		j++
+	}
+	if j >= len(line) || line[j] != 'r' {
+		return i
+	}
+	j++
+	for j < len(line) && line[j] == '#' {
+		j++
+	}
+	if j >= len(line) || line[j] != '"' {
+		return i
+	}
+	return j + 1
+}
+
+// isCharLiteral reports whether the quote at line[i] opens a Rust char
+// literal like 'x', '{' or '\n' rather than a lifetime like 'a.
+func isCharLiteral(line string, i int) bool {
+	if i+1 >= len(line) {
+		return false
+	}
+	if line[i+1] == '\\' {
+		return true
+	}
+	_, size := utf8.DecodeRuneInString(line[i+1:])
+	return i+1+size < len(line) && line[i+1+size] == '\''
+}
+
+// regexpEnd returns the index just past the regexp literal and its flags
+// starting at line[start]. A "/" inside a [...] class does not end it.
+func regexpEnd(line string, start int) int {
+	inClass := false
+	for j := start + 1; j < len(line); j++ {
+		switch line[j] {
+		case '\\':
+			j++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if inClass {
+				continue
+			}
+			j++
+			for j < len(line) && isWordByte(line[j]) {
+				j++
+			}
+			return j
+		}
+	}
+	return len(line)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,79 @@
+package mapper
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBraceLexer(t *testing.T) {
+	tests := []struct {
+		name   string
+		syntax braceSyntax
+		lines  []string
+		want   []string
+	}{
+		{"line comment", jsSyntax,
+			[]string{`f() { // }`},
+			[]string{`f() {     `}},
+		{"no line comments in CSS", cssSyntax,
+			[]string{`a { background: url(//x) }`},
+			[]string{`a { background: url(//x) }`}},
+		{"block comment across lines", javaSyntax,
+			[]string{`int a; /* {`, `} */ int b;`},
+			[]string{`int a;     `, `     int b;`}},
+		{"strings", javaSyntax,
+			[]string{`s = "{" + '}';`},
+			[]string{`s =     +    ;`}},
+		{"escaped quote", javaSyntax,
+			[]string{`s = "\"{";`},
+			[]string{`s =      ;`}},
+		{"template literal with expression", jsSyntax,
+			[]string{"x = `a{${f({})}`"},
+			[]string{"x =      f({})  "}},
+		{"template across lines", jsSyntax,
+			[]string{"x = `{", "}` + y"},
+			[]string{"x =   ", "   + y"}},
+		{"regexp after operator", jsSyntax,
+			[]string{`r = /{/g`},
+			[]string{`r =     `}},
+		{"division", jsSyntax,
+			[]string{`x = a / b / c`},
+			[]string{`x = a / b / c`}},
+		{"regexp after return", jsSyntax,
+			[]string{`return /}/`},
+			[]string{`return    `}},
+		{"Java text block", javaSyntax,
+			[]string{`s = """`, `{`, `""";`},
+			[]string{`s =    `, ` `, `   ;`}},
+		{"C# verbatim string", csharpSyntax,
+			[]string{`s = @"a""{`, `}";`},
+			[]string{`s =       `, `  ;`}},
+		{"Rust raw string", rustSyntax,
+			[]string{`s = r#"{"#;`},
+			[]string{`s =       ;`}},
+		{"Rust multi-line string", rustSyntax,
+			[]string{`s = "{`, `}";`},
+			[]string{`s =   `, `  ;`}},
+		{"Rust lifetime", rustSyntax,
+			[]string{`fn f<'a>(x: &'a str) {`},
+			[]string{`fn f<'a>(x: &'a str) {`}},
+		{"Rust char literal", rustSyntax,
+			[]string{`c = '{';`},
+			[]string{`c =    ;`}},
+		{"C block comment", cSyntax,
+			[]string{`int a; /* { */ int b; // }`},
+			[]string{`int a;         int b;     `}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := braceLexer{syntax: tt.syntax}
+			var got []string
+			for _, line := range tt.lines {
+				got = append(got, l.code(line))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package mapper
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// scopeStyle decides how a lineParser tracks where an open region ends.
+type scopeStyle int
+
+const (
+	scopeNone   scopeStyle = iota // point regions only (markers, headings)
+	scopeBraces                   // "{...}" and "(...)" nesting
+	scopeTags                     // HTML-style </tag> closing
+)
+
+// lineMatch describes a region opened on the current line.
+type lineMatch struct {
+	Kind     Kind
+	Name     string
+	Exported bool
+	// CloseChar is "}" or ")" for brace languages, or ";" for a statement
+	// that ends at a semicolon or a blank line, like a multi-line type alias
+	CloseChar string
+	Tag       string // Lower-cased tag name (for scopeTags)
+}
+
+// lineParser is the line-oriented scanner behind every regex-driven language.
+// Each language supplies how scopes close, how a line opens a region and how
+// a line declares a dependency; the scope stack, manual "// 1." markers,
+// doc comments, signatures and parents are shared.
+type lineParser struct {
+	style  scopeStyle
+	syntax braceSyntax // comments and literals skipped by scopeBraces
+	// attribute reports whether a line is an attribute of the declaration
+	// below it, like Rust's #[derive(Debug)], and whether it makes a
+	// function a test, like #[test]
+	attribute func(trimmed string) (ok, test bool)
+	detect    func(text string) (lineMatch, bool)
+	// member, when set, detects the lines inside a region instead of
+	// detect, given the enclosing region's kind and whether the line is
+	// directly in its body: a method in a class body, where the same text
+	// in a function body is a call
+	member  func(text string, parent Kind, direct bool) (lineMatch, bool)
+	depends func(text string) string
+	// overloads folds bodiless signatures ("f(a: string): void;") into the
+	// function of the same name that follows them
+	overloads bool
+}
+
+// Parse implements Parser.
+func (p *lineParser) Parse(src []byte) []Region {
+	var regions []Region
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+
+	// --- SMART PARSING STATE ---
+	type Scope struct {
+		Region    Region
+		OpenLevel int    // The brace/paren level when this region started
+		CloseChar string // "}" or ")" (for brace languages)
+		Tag       string // For HTML matching
+	}
+	var scopeStack []Scope
+
+	// Counting State
+	braceLevel := 0
+	parenLevel := 0
+	lex := braceLexer{syntax: p.syntax}
+	var doc []string // comment lines right above the current line
+	isTest := false  // an attribute above made the next function a test
+	attrParen := -1  // the paren level a multi-line attribute closes at
+
+	// A signature without a body, which may be an overload
+	var overload *Region
+	flush := func() {
+		if overload != nil {
+			regions = append(regions, *overload)
+			overload = nil
+		}
+	}
+	// fold takes the pending overload into the function it declares
+	fold := func(region *Region) {
+		if overload == nil {
+			return
+		}
+		if overload.Kind != region.Kind || overload.Name != region.Name || overload.Parent != region.Parent {
+			flush()
+			return
+		}
+		// The signatures document the function better than its implementation
+		region.Start, region.Signature = overload.Start, overload.Signature
+		if overload.Doc != "" {
+			region.Doc = overload.Doc
+		}
+		overload = nil
+	}
+
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+
+		// 0. Update Levels, from code only: braces in comments and strings don't nest
+		code, inCode, inComment := text, true, false
+		if p.style == scopeBraces {
+			inCode, inComment = lex.inCode(), lex.mode == lexBlockComment
+			code = lex.code(text)
+			braceLevel += strings.Count(code, "{")
+			braceLevel -= strings.Count(code, "}")
+			parenLevel += strings.Count(code, "(")
+			parenLevel -= strings.Count(code, ")")
+		}
+
+		// 1. Check for Scope CLOSURE based on state
+		if len(scopeStack) > 0 {
+			closedCount := 0
+			// Iterate backwards to close deeper scopes first
+			for i := len(scopeStack) - 1; i >= 0; i-- {
+				scope := &scopeStack[i]
+				shouldClose := false
+
+				switch p.style {
+				case scopeBraces:
+					if scope.CloseChar == "}" {
+						if braceLevel <= scope.OpenLevel && strings.Contains(code, "}") {
+							shouldClose = true
+						}
+					} else if scope.CloseChar == ")" {
+						if parenLevel <= scope.OpenLevel && strings.Contains(code, ")") {
+							shouldClose = true
+						}
+					} else if scope.CloseChar == ";" && braceLevel <= scope.OpenLevel {
+						if strings.HasSuffix(strings.TrimSpace(code), ";") {
+							shouldClose = true
+						} else if trimmed == "" || jsStatementRe.MatchString(code) {
+							// Ended on the line above, without a semicolon
+							scope.Region.End = lineNum - 1
+							regions = append(regions, scope.Region)
+							closedCount++
+							continue
+						}
+					}
+				case scopeTags:
+					// HTML Closure: Look for </tag>
+					if scope.Tag != "" && strings.Contains(strings.ToLower(text), "</"+scope.Tag+">") {
+						shouldClose = true
+					}
+				}
+
+				if shouldClose {
+					flush()
+					scope.Region.End = lineNum
+					regions = append(regions, scope.Region)
+					closedCount++
+				} else {
+					break
+				}
+			}
+			if closedCount > 0 {
+				scopeStack = scopeStack[:len(scopeStack)-closedCount]
+			}
+		}
+
+		// Lines inside a block comment or a multi-line string declare nothing
+		if !inCode {
+			if inComment {
+				doc = append(doc, text)
+			} else {
+				doc = nil
+			}
+			continue
+		}
+
+		// 2. Manual Markers (Override everything)
+		if strings.Contains(text, "//") {
+			if cleanName, ok := markerName(text); ok {
+				if len(cleanName) > 0 {
+					regions = append(regions, Region{Start: lineNum, End: lineNum, Kind: KindMarker, Name: cleanName})
+				}
+				doc = nil
+				continue
+			}
+		}
+		if p.isComment(trimmed) {
+			doc = append(doc, text)
+			continue
+		}
+		if attrParen >= 0 {
+			// The arguments of a multi-line attribute
+			if parenLevel <= attrParen {
+				attrParen = -1
+			}
+			continue
+		}
+		if p.attribute != nil {
+			if ok, test := p.attribute(trimmed); ok {
+				isTest = isTest || test
+				if opened := parenLevel - strings.Count(code, "(") + strings.Count(code, ")"); parenLevel > opened {
+					attrParen = opened
+				}
+				continue
+			}
+		}
+
+		// 3. Check for NEW Region Start
+		var m lineMatch
+		var matched bool
+		if top := len(scopeStack) - 1; p.member != nil && top >= 0 {
+			before := braceLevel - strings.Count(code, "{") + strings.Count(code, "}")
+			m, matched = p.member(text, scopeStack[top].Region.Kind, before == scopeStack[top].OpenLevel+1)
+		} else if p.detect != nil {
+			m, matched = p.detect(text)
+		}
+		if matched && isTest && m.Kind == KindFunction {
+			m.Kind = KindTest
+		}
+		isTest = false
+
+		// Check for Dependencies
+		if p.depends != nil {
+			if depName := p.depends(text); depName != "" {
+				// Dependencies are single lines, never pushed on the scope stack
+				regions = append(regions, Region{Start: lineNum, End: lineNum, Kind: KindImport, Name: depName})
+			}
+		}
+
+		if !matched {
+			if trimmed != "" {
+				flush()
+			}
+			doc = nil
+			continue
+		}
+
+		// PUSH NEW SCOPE
+		region := Region{Start: lineNum, Kind: m.Kind, Name: m.Name, Exported: m.Exported, Doc: commentText(doc)}
+		doc = nil
+		if m.Kind.hasSignature() {
+			region.Signature = signature(text)
+		}
+		closeChar := m.CloseChar
+		if closeChar == "" {
+			closeChar = "}"
+		}
+		startParam := 0
+		switch p.style {
+		case scopeBraces:
+			if closeChar == "}" {
+				startParam = braceLevel
+				if strings.Contains(code, "{") {
+					startParam--
+				}
+			} else if closeChar == ")" {
+				startParam = parenLevel
+				if strings.Contains(code, "(") {
+					startParam--
+				}
+			} else {
+				startParam = braceLevel - strings.Count(code, "{") + strings.Count(code, "}")
+			}
+		}
+
+		// Determine Parent Context: functions inside a class, object,
+		// interface, impl or module are its methods, tests and suites nest in
+		// suites and tests in modules
+		for i := len(scopeStack) - 1; i >= 0; i-- {
+			parent := scopeStack[i].Region
+			if (region.Kind == KindFunction || region.Kind == KindMethod) && parent.Kind.isScope() {
+				region.Kind, region.Parent = KindMethod, parent.Name
+				break
+			}
+			if (region.Kind == KindTest || region.Kind == KindSuite) && parent.Kind == KindSuite ||
+				region.Kind == KindTest && parent.Kind == KindModule {
+				region.Parent = parent.Name
+				break
+			}
+		}
+
+		// A body that closes on its opening line, or no body at all
+		// ("fn len(&self) -> usize;", "struct Unit;"), is a single line
+		if p.style == scopeBraces && closeChar != ";" {
+			open, level := "{", braceLevel
+			if closeChar == ")" {
+				open, level = "(", parenLevel
+			}
+			opened := strings.Count(code, open)
+			before := level - opened + strings.Count(code, closeChar)
+			if opened > 0 && level <= before || opened == 0 && strings.HasSuffix(strings.TrimSpace(code), ";") {
+				if region.Signature != "" && open == "{" {
+					region.Signature = signature(text[:bodyStart(code)])
+				}
+				region.End = lineNum
+				fold(&region)
+				if p.overloads && opened == 0 && (region.Kind == KindFunction || region.Kind == KindMethod) {
+					overload = &region
+					continue
+				}
+				regions = append(regions, region)
+				continue
+			}
+		}
+		// An element closed on its opening line, like <p>...</p>
+		if p.style == scopeTags && m.Tag != "" {
+			lower := strings.ToLower(text)
+			if strings.Count(lower, "</"+m.Tag+">") >= strings.Count(lower, "<"+m.Tag) {
+				region.End = lineNum
+				regions = append(regions, region)
+				continue
+			}
+		}
+		fold(&region)
+		if closeChar == ";" && strings.HasSuffix(strings.TrimSpace(code), ";") {
+			region.End = lineNum
+			regions = append(regions, region)
+			continue
+		}
+
+		scopeStack = append(scopeStack, Scope{
+			Region:    region,
+			OpenLevel: startParam,
+			CloseChar: closeChar,
+			Tag:       m.Tag,
+		})
+	}
+
+	// Close remaining scopes at end of file
+	flush()
+	for i := len(scopeStack) - 1; i >= 0; i-- {
+		scope := &scopeStack[i]
+		scope.Region.End = lineNum
+		regions = append(regions, scope.Region)
+	}
+
+	return regions
+}
+
+// isComment reports whether a trimmed line outside block comments is a
+// comment, which may document the declaration below it.
+func (p *lineParser) isComment(trimmed string) bool {
+	switch p.style {
+	case scopeBraces:
+		return strings.HasPrefix(trimmed, "/*") || (p.syntax.lineComments && strings.HasPrefix(trimmed, "//"))
+	}
+	return false
+}
+
+// bodyStart returns the index of the "{" opening the body on a blanked code
+// line: the first one outside parentheses, so destructured parameters are
+// not mistaken for it. It is len(code) when there is none.
+func bodyStart(code string) int {
+	depth := 0
+	for i, c := range code {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '{':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(code)
+}
@@ -0,0 +1,389 @@
+package mapper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+const (
+	// ManifestFile records what every map was generated from, at the scan
+	// root or its mirror under the output directory
+	ManifestFile = ".astrmap-manifest.json"
+
+	// ParserVersion is stored with every manifest entry. Bump it whenever a
+	// parser change alters map output so the next scan regenerates everything.
+	ParserVersion = 14
+)
+
+// ManifestEntry describes the source file a map was generated from.
+type ManifestEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"` // UnixNano
+	Hash    string `json:"hash"`  // SHA-256 of the content
+	Parser  int    `json:"parser"`
+}
+
+// Manifest tracks the mapped files below a root so scans can skip files
+// whose content did not change since their map was written.
+type Manifest struct {
+	Format string                   `json:"format"`
+	Detail bool                     `json:"detail,omitempty"`
+	Files  map[string]ManifestEntry `json:"files"` // keyed by slash path relative to root
+	// Folders holds the inventory digest each folder's maps were written
+	// for, keyed like Files, so that unmapped files refresh them too
+	Folders map[string]string `json:"folders,omitempty"`
+
+	root  string
+	path  string // where the manifest is stored
+	mu    sync.Mutex
+	dirty bool
+}
+
+// LoadManifest reads the manifest of root. A missing or unreadable manifest
+// yields an empty one, which makes every file count as changed.
+func LoadManifest(cfg config.Config, root string) *Manifest {
+	m := &Manifest{
+		root:    root,
+		path:    filepath.Join(outputPath(cfg, root), ManifestFile),
+		Files:   make(map[string]ManifestEntry),
+		Folders: make(map[string]string),
+	}
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return m
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		log.Printf("⚠️ Ignoring corrupt manifest: %v", err)
+		m.Files = make(map[string]ManifestEntry)
+		m.Folders = nil
+		m.Format = ""
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]ManifestEntry)
+	}
+	if m.Folders == nil {
+		m.Folders = make(map[string]string)
+	}
+	return m
+}
+
+// Save writes the manifest back if anything changed.
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	if err := writeJSON(m.path, m); err != nil {
+		return err
+	}
+	m.dirty = false
+	return nil
+}
+
+// Reset forgets every entry, forcing a full rebuild.
+func (m *Manifest) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Format = ""
+	m.Detail = false
+	m.Files = make(map[string]ManifestEntry)
+	m.Folders = make(map[string]string)
+	m.dirty = true
+}
+
+// outdated reports whether the maps were written with other settings than
+// cfg, which makes them all stale.
+func (m *Manifest) outdated(cfg config.Config) bool {
+	return m.Format != cfg.MapFormat() || m.Detail != cfg.Detail
+}
+
+func (m *Manifest) key(path string) string {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Changed reports whether path needs a new map: it is new, its map is
+// missing, the parser changed, or its content hash differs.
+// A file that was only touched (same hash, new mtime) is not changed.
+func (m *Manifest) Changed(cfg config.Config, path string, info os.FileInfo) bool {
+	m.mu.Lock()
+	e, ok := m.Files[m.key(path)]
+	m.mu.Unlock()
+
+	if !ok || e.Parser != ParserVersion || !mapExists(cfg, path) {
+		return true
+	}
+	if e.Size == info.Size() && e.ModTime == info.ModTime().UnixNano() {
+		return false
+	}
+	hash, err := hashFile(path)
+	if err != nil || hash != e.Hash {
+		return true
+	}
+
+	// Touched but identical: remember the new mtime to skip hashing next time
+	m.mu.Lock()
+	e.Size, e.ModTime = info.Size(), info.ModTime().UnixNano()
+	m.Files[m.key(path)] = e
+	m.dirty = true
+	m.mu.Unlock()
+	return false
+}
+
+// Record stores the current state of path after its map was written.
+func (m *Manifest) Record(cfg config.Config, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Format = cfg.MapFormat()
+	m.Detail = cfg.Detail
+	m.Files[m.key(path)] = ManifestEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    hash,
+		Parser:  ParserVersion,
+	}
+	m.dirty = true
+}
+
+// Forget drops path from the manifest.
+func (m *Manifest) Forget(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Files[m.key(path)]; ok {
+		delete(m.Files, m.key(path))
+		m.dirty = true
+	}
+}
+
+// Prune forgets every entry not in current and returns their absolute paths.
+func (m *Manifest) Prune(current []string) []string {
+	keep := make(map[string]bool, len(current))
+	for _, p := range current {
+		keep[m.key(p)] = true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var removed []string
+	for k := range m.Files {
+		if !keep[k] {
+			removed = append(removed, filepath.Join(m.root, filepath.FromSlash(k)))
+			delete(m.Files, k)
+			m.dirty = true
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// folderChanged reports whether the folder maps of dir were written for
+// another inventory than the one digest sums up. Without a digest, the
+// folder could not be listed and always counts as changed.
+func (m *Manifest) folderChanged(dir, digest string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return digest == "" || m.Folders[m.key(dir)] != digest
+}
+
+// recordFolder stores the inventory digest of dir after its folder maps
+// were written.
+func (m *Manifest) recordFolder(dir, digest string) {
+	if digest == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Folders[m.key(dir)] != digest {
+		m.Folders[m.key(dir)] = digest
+		m.dirty = true
+	}
+}
+
+// pruneFolders forgets the digests of folders not in current.
+func (m *Manifest) pruneFolders(current map[string]string) {
+	keep := make(map[string]bool, len(current))
+	for dir := range current {
+		keep[m.key(dir)] = true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k := range m.Folders {
+		if !keep[k] {
+			delete(m.Folders, k)
+			m.dirty = true
+		}
+	}
+}
+
+// SyncResult summarizes an incremental scan.
+type SyncResult struct {
+	Mapped    []string        // files whose map was (re)generated
+	Removed   []string        // files that disappeared since the last scan
+	DirtyDirs map[string]bool // directories whose folder maps are stale
+	Errors    []error         // files that failed to map, in file order
+}
+
+// Err joins the errors of the files that failed to map, or returns nil.
+func (r SyncResult) Err() error {
+	return errors.Join(r.Errors...)
+}
+
+// Sync regenerates the maps of the files that changed since the manifest at
+// root was written, removes maps of files that disappeared and reports which
+// directories need new folder maps. With force, every file is remapped.
+// Files are checked and mapped on cfg.WorkerCount() workers; the result
+// lists them in the order of files. A file that fails to map is not
+// recorded, so the next Sync retries it.
+func Sync(cfg config.Config, root string, files []string, force bool) SyncResult {
+	m := LoadManifest(cfg, root)
+	// Maps written in another output format or detail level are all stale
+	if force || m.outdated(cfg) {
+		m.Reset()
+	}
+
+	stale := make([]bool, len(files))
+	errs := make([]error, len(files))
+	forEach(files, cfg.WorkerCount(), func(i int, f string) {
+		info, err := os.Stat(f)
+		if err != nil || !m.Changed(cfg, f, info) {
+			return
+		}
+		stale[i] = true
+		if errs[i] = GenerateMap(cfg, f); errs[i] == nil {
+			m.Record(cfg, f)
+		}
+	})
+
+	res := SyncResult{DirtyDirs: make(map[string]bool)}
+	for i, f := range files {
+		switch {
+		case !stale[i]:
+		case errs[i] != nil:
+			res.Errors = append(res.Errors, errs[i])
+		default:
+			res.Mapped = append(res.Mapped, f)
+			markDirty(res.DirtyDirs, root, filepath.Dir(f))
+		}
+	}
+
+	res.Removed = m.Prune(files)
+	for _, f := range res.Removed {
+		RemoveMaps(cfg, f)
+		pruneMirror(cfg, filepath.Dir(f))
+		markDirty(res.DirtyDirs, root, filepath.Dir(f))
+	}
+
+	if err := m.Save(); err != nil {
+		log.Printf("❌ Failed to write manifest: %v", err)
+	}
+	return res
+}
+
+// Pending reports what Sync would do without writing anything: the files
+// whose maps are stale and the files that disappeared.
+func Pending(cfg config.Config, root string, files []string, force bool) SyncResult {
+	m := LoadManifest(cfg, root)
+	if force || m.outdated(cfg) {
+		m.Reset()
+	}
+
+	stale := make([]bool, len(files))
+	forEach(files, cfg.WorkerCount(), func(i int, f string) {
+		info, err := os.Stat(f)
+		stale[i] = err == nil && m.Changed(cfg, f, info)
+	})
+
+	res := SyncResult{DirtyDirs: make(map[string]bool)}
+	for i, f := range files {
+		if stale[i] {
+			res.Mapped = append(res.Mapped, f)
+			markDirty(res.DirtyDirs, root, filepath.Dir(f))
+		}
+	}
+	// Prune only touches the in-memory manifest, which is never saved here
+	res.Removed = m.Prune(files)
+	for _, f := range res.Removed {
+		markDirty(res.DirtyDirs, root, filepath.Dir(f))
+	}
+	return res
+}
+
+// markDirty flags dir and every ancestor up to root: their _level_3 maps
+// embed the maps of everything below them.
+func markDirty(dirs map[string]bool, root, dir string) {
+	if rel, err := filepath.Rel(root, dir); err != nil || strings.HasPrefix(rel, "..") {
+		return
+	}
+	for {
+		dirs[dir] = true
+		if dir == root {
+			return
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+		dir = parent
+	}
+}
+
+// RemoveMaps deletes the file maps of the source file at path.
+func RemoveMaps(cfg config.Config, path string) {
+	base := outputPath(cfg, path)
+	os.Remove(base + TextMapSuffix)
+	os.Remove(base + JSONMapSuffix)
+}
+
+// mapExists reports whether the map cfg asks for exists for path.
+func mapExists(cfg config.Config, path string) bool {
+	base := outputPath(cfg, path)
+	if cfg.WritesText() {
+		if _, err := os.Stat(base + TextMapSuffix); err != nil {
+			return false
+		}
+	}
+	if cfg.WritesJSON() {
+		if _, err := os.Stat(base + JSONMapSuffix); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
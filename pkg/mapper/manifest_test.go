@@ -0,0 +1,84 @@
+package mapper
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(t *testing.T, root string, cfg *config.Config)
+		force       bool
+		wantMapped  []string
+		wantRemoved []string
+	}{
+		{"unchanged", func(t *testing.T, root string, cfg *config.Config) {}, false, nil, nil},
+		{"touched", func(t *testing.T, root string, cfg *config.Config) {
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(filepath.Join(root, "a.go"), later, later); err != nil {
+				t.Fatal(err)
+			}
+		}, false, nil, nil},
+		{"edited", func(t *testing.T, root string, cfg *config.Config) {
+			writeFile(t, filepath.Join(root, "a.go"), "package a\n\nfunc A() {}\n")
+		}, false, []string{"a.go"}, nil},
+		{"added", func(t *testing.T, root string, cfg *config.Config) {
+			writeFile(t, filepath.Join(root, "sub", "c.go"), "package sub\n")
+		}, false, []string{"sub/c.go"}, nil},
+		{"removed", func(t *testing.T, root string, cfg *config.Config) {
+			if err := os.Remove(filepath.Join(root, "sub", "b.go")); err != nil {
+				t.Fatal(err)
+			}
+		}, false, nil, []string{"sub/b.go"}},
+		{"map deleted", func(t *testing.T, root string, cfg *config.Config) {
+			if err := os.Remove(filepath.Join(root, "a.go"+TextMapSuffix)); err != nil {
+				t.Fatal(err)
+			}
+		}, false, []string{"a.go"}, nil},
+		{"detail turned on", func(t *testing.T, root string, cfg *config.Config) {
+			cfg.Detail = true
+		}, false, []string{"a.go", "sub/b.go"}, nil},
+		{"forced", func(t *testing.T, root string, cfg *config.Config) {}, true, []string{"a.go", "sub/b.go"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, "a.go"), "package a\n")
+			writeFile(t, filepath.Join(root, "sub", "b.go"), "package sub\n")
+			cfg := config.Config{Roots: []config.RootConfig{{Path: root, AllowedExts: []string{".go"}}}}
+			if res := Sync(cfg, root, CollectFiles(cfg), false); len(res.Mapped) != 2 {
+				t.Fatalf("first sync mapped %v, want both files", res.Mapped)
+			}
+
+			tt.change(t, root, &cfg)
+			res := Sync(cfg, root, CollectFiles(cfg), tt.force)
+			if got := relPaths(t, root, res.Mapped); !slices.Equal(got, tt.wantMapped) {
+				t.Errorf("mapped %q, want %q", got, tt.wantMapped)
+			}
+			if got := relPaths(t, root, res.Removed); !slices.Equal(got, tt.wantRemoved) {
+				t.Errorf("removed %q, want %q", got, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+// relPaths returns paths as slash paths relative to root, sorted.
+func relPaths(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	var rels []string
+	for _, p := range paths {
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+	slices.Sort(rels)
+	return rels
+}
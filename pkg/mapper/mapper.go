@@ -1,7 +1,9 @@
 package mapper
 
 import (
-	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
@@ -9,461 +11,93 @@ import (
 	"regexp"
 	"sort"
 	"strings"
-	"sync"
 	"time"
-	"unicode"
 
 	"github.com/hubby247/astrmap/pkg/config"
 	"github.com/hubby247/astrmap/pkg/fs"
 )
 
-type Region struct {
-	Start int
-	End   int
-	Name  string
-}
+// markerRe matches manual region markers such as "// 1. Setup",
+// "// #region Handlers" or "// === Routes ===".
+var markerRe = regexp.MustCompile(`^\s*//\s*(?:(\d+)\.|#region|={3})\s*(.*)$`)
 
-// GenerateMap scans a single file and creates a .map.txt file
-func GenerateMap(path string) {
-	if strings.HasSuffix(path, ".map.txt") || strings.HasSuffix(path, "codemap.json") || strings.HasSuffix(path, "watchlist.txt") {
-		return
+// markerName extracts the title of a manual marker line. ok reports whether
+// the line is a marker at all; the title may still be empty.
+func markerName(line string) (name string, ok bool) {
+	matches := markerRe.FindStringSubmatch(line)
+	if len(matches) != 3 {
+		return "", false
 	}
+	rawName := strings.TrimSpace(matches[2])
+	// Clean up separators
+	return strings.TrimFunc(rawName, func(r rune) bool {
+		return r == '=' || r == '-' || r == ' ' || r == '\t'
+	}), true
+}
 
-	file, err := os.Open(path)
-	if err != nil {
-		log.Printf("❌ Failed to open source: %s (%v)", filepath.Base(path), err)
-		return
+// GenerateMap scans a single file and writes its map next to it (or to its
+// mirror under cfg.OutDir): a .map.txt, a .map.json or both, depending on
+// cfg.Format. It is safe to call for different files concurrently.
+func GenerateMap(cfg config.Config, path string) error {
+	if IsMapFile(path) || strings.HasSuffix(path, "codemap.json") || strings.HasSuffix(path, "watchlist.txt") || strings.HasSuffix(path, ManifestFile) {
+		return nil
 	}
-	defer file.Close()
 
-	// Metadata
-	info, err := file.Stat()
+	fm, err := BuildFileMap(path)
 	if err != nil {
-		return
+		return err // already names the file
+	}
+	if !cfg.Detail {
+		fm.stripDetail()
 	}
-	modTime := info.ModTime().Format("2006-01-02 15:04:05")
-	sizeKB := float64(info.Size()) / 1024.0
-
-	ext := strings.ToLower(filepath.Ext(path))
-	var regions []Region
-	var scanner = bufio.NewScanner(file)
-
-	// --- SMART PARSING STATE ---
-	type Scope struct {
-		Region    Region
-		OpenLevel int    // The brace/paren level when this region started
-		Indent    int    // Indentation level (for Python)
-		CloseChar string // "}" or ")" or "</tag>" (for python or html)
-		Tag       string // For HTML matching
-	}
-	var scopeStack []Scope
-
-	// Counting State
-	braceLevel := 0
-	parenLevel := 0
-
-	// Language Config
-	isBraceLang := (ext == ".go" || ext == ".js" || ext == ".ts" || ext == ".jsx" || ext == ".tsx" || ext == ".java" || ext == ".cs" || ext == ".css" || ext == ".scss" || ext == ".less")
-	isPython := (ext == ".py")
-	isHtml := (ext == ".html" || ext == ".htm" || ext == ".xml" || ext == ".vue" || ext == ".php")
-
-	lineNum := 0
-
-	// Regex Patterns
-	codeRe := regexp.MustCompile(`^\s*//\s*(?:(\d+)\.|#region|={3})\s*(.*)$`)
-	mdRe := regexp.MustCompile(`^(#+)\s+(.*)$`)
-
-	// HTML: Matches <tag ... > or </tag>
-	htmlTagRe := regexp.MustCompile(`<\s*([a-zA-Z0-9-]+)\b([^>]*)>|<\s*/\s*([a-zA-Z0-9-]+)\s*>`)
-
-	// GO
-	goFuncRe := regexp.MustCompile(`^func\s+(?:\([^)]+\)\s+)?([A-Z][a-zA-Z0-9_]*)\s*\(`)
-	goTypeRe := regexp.MustCompile(`^type\s+([A-Z][a-zA-Z0-9_]*)\s+(struct|interface)`)
-	goImportRe := regexp.MustCompile(`^import\s*\(`)
-	goConstRe := regexp.MustCompile(`^const\s*\(`)
-	goVarRe := regexp.MustCompile(`^var\s*\(`)
-
-	// JS/TS
-	jsFuncRe := regexp.MustCompile(`^(?:export\s+)?(?:async\s+)?function\s+([a-zA-Z0-9_]+)\s*\(`)
-	jsClassRe := regexp.MustCompile(`^(?:export\s+)?class\s+([a-zA-Z0-9_]+)`)
-	jsArrowRe := regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([a-zA-Z0-9_]+)\s*=\s*(?:async\s*)?(?:\([^)]*\)|[a-zA-Z0-9_]+)\s*=>`)
-	tsInterfaceRe := regexp.MustCompile(`^(?:export\s+)?interface\s+([a-zA-Z0-9_]+)`)
-
-	// JS Testing & Objects
-	jsDescribeRe := regexp.MustCompile(`^(?:describe|context|suite)\s*\(\s*["']([^"']+)["']`)
-	jsItRe := regexp.MustCompile(`^(?:it|test)\s*\(\s*["']([^"']+)["']`)
-	jsObjRe := regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([a-zA-Z0-9_]+)\s*=\s*\{`)
-	jsExportDefaultRe := regexp.MustCompile(`^export\s+default\s*\{`)
-	jsRouteRe := regexp.MustCompile(`^(?:router|app)\.(get|post|put|delete|patch|use)\s*\(\s*["']([^"']+)["']`)
-
-	// JS Granular: Methods, Properties
-	// RE2 Compatible: No negative lookahead. match broadly, filter later.
-	jsMethodRe := regexp.MustCompile(`^\s*(?:async\s+)?([a-zA-Z0-9_]+)\s*\([^)]*\)\s*\{`)
-
-	jsPropFuncRe := regexp.MustCompile(`^\s*([a-zA-Z0-9_]+)\s*:\s*(?:async\s+)?function\s*\(`)
-	jsPropArrowRe := regexp.MustCompile(`^\s*([a-zA-Z0-9_]+)\s*:\s*(?:async\s+)?(?:\([^)]*\)|[a-zA-Z0-9_]+)\s*=>`)
-
-	// Ignore keywords for jsMethodRe
-	jsKeywords := map[string]bool{
-		"if": true, "for": true, "while": true, "switch": true,
-		"catch": true, "function": true, "return": true, "await": true, "else": true,
-	}
-
-	// CSS/SCSS
-	cssRe := regexp.MustCompile(`^([^{]+)\{\s*$`)
-
-	// Dependencies Extractors (Imports)
-	depGoRe := regexp.MustCompile(`^\s*import\s*(?:\(\s*)?["']([^"']+)["']`)
-	depJsRe := regexp.MustCompile(`^\s*(?:import\s+.*from\s+|require\s*\(\s*)["']([^"']+)["']`)
-	depHtmlScriptRe := regexp.MustCompile(`<\s*script\s+[^>]*src=["']([^"']+)["']`)
-	depHtmlLinkRe := regexp.MustCompile(`<\s*link\s+[^>]*href=["']([^"']+)["'][^>]*rel=["']stylesheet["']|<\s*link\s+[^>]*rel=["']stylesheet["'][^>]*href=["']([^"']+)["']`)
-	depCssRe := regexp.MustCompile(`@import\s*(?:url\s*\(\s*)?["']?([^"'\)]+)["']?`)
-
-	// Python
-	pyDefRe := regexp.MustCompile(`^(\s*)def\s+([a-zA-Z0-9_]+)\s*\(`)
-	pyClassRe := regexp.MustCompile(`^(\s*)class\s+([a-zA-Z0-9_]+)`)
-
-	// Java/C#
-	javaClassRe := regexp.MustCompile(`^\s*(?:public|protected|private)?\s*(?:static\s+)?(?:final\s+)?class\s+([a-zA-Z0-9_]+)`)
-	javaMethodRe := regexp.MustCompile(`^\s*(?:public|protected|private)\s+(?:static\s+)?(?:final\s+)?[\w<>\[\]]+\s+([a-zA-Z0-9_]+)\s*\(`)
-
-	for scanner.Scan() {
-		lineNum++
-		text := scanner.Text()
-		trimmed := strings.TrimSpace(text)
-
-		// 0. Update Levels
-		if isBraceLang {
-			braceLevel += strings.Count(text, "{")
-			braceLevel -= strings.Count(text, "}")
-			parenLevel += strings.Count(text, "(")
-			parenLevel -= strings.Count(text, ")")
-		}
-
-		// 1. Check for Scope CLOSURE based on state
-		if len(scopeStack) > 0 {
-			closedCount := 0
-			// Iterate backwards to close deeper scopes first
-			for i := len(scopeStack) - 1; i >= 0; i-- {
-				scope := &scopeStack[i]
-				shouldClose := false
-
-				if isBraceLang {
-					if scope.CloseChar == "}" {
-						if braceLevel <= scope.OpenLevel && strings.Contains(text, "}") {
-							shouldClose = true
-						}
-					} else if scope.CloseChar == ")" {
-						if parenLevel <= scope.OpenLevel && strings.Contains(text, ")") {
-							shouldClose = true
-						}
-					}
-				} else if isPython {
-					if trimmed != "" {
-						currentIndent := len(text) - len(strings.TrimLeft(text, " "))
-						if currentIndent <= scope.Indent {
-							shouldClose = true
-						}
-					}
-				} else if isHtml {
-					// HTML Closure: Look for </tag>
-					if scope.Tag != "" && strings.Contains(strings.ToLower(text), "</"+strings.ToLower(scope.Tag)+">") {
-						shouldClose = true
-					}
-				}
-
-				if shouldClose {
-					scope.Region.End = lineNum
-					regions = append(regions, scope.Region)
-					closedCount++
-				} else {
-					break
-				}
-			}
-			if closedCount > 0 {
-				scopeStack = scopeStack[:len(scopeStack)-closedCount]
-			}
-		}
-
-		// 2. Check for NEW Region Start
-		var name string
-		var matched bool
-		var newIndent int = 0
-		var closeChar string = "}" // Default for brace langs
-		var tagName string
-
-		// Manual Markers (Override everything)
-		if strings.Contains(text, "//") {
-			matches := codeRe.FindStringSubmatch(text)
-			if len(matches) == 3 {
-				rawName := strings.TrimSpace(matches[2])
-				// Clean up separators
-				cleanName := strings.TrimFunc(rawName, func(r rune) bool {
-					return r == '=' || r == '-' || r == ' ' || r == '\t'
-				})
-
-				if len(cleanName) > 0 {
-					name = "📍 " + cleanName
-					regions = append(regions, Region{Start: lineNum, End: lineNum, Name: name})
-				}
-				continue
-			}
-		}
-
-		// Auto-Detect
-		if isBraceLang {
-			if ext == ".go" {
-				if m := goFuncRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "ƒ " + m[1]
-					matched = true
-				} else if m := goTypeRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "📦 " + m[1] + " (" + m[2] + ")"
-					matched = true
-				} else if goImportRe.MatchString(text) {
-					name = "📥 Imports"
-					matched = true
-					closeChar = ")"
-				} else if goConstRe.MatchString(text) {
-					name = "🧱 Const"
-					matched = true
-					closeChar = ")"
-				} else if goVarRe.MatchString(text) {
-					name = "🔨 Var"
-					matched = true
-					closeChar = ")"
-				}
-			} else if ext == ".js" || ext == ".ts" || ext == ".jsx" || ext == ".tsx" {
-				if m := jsFuncRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "ƒ " + m[1]
-					matched = true
-				} else if m := jsClassRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "📦 " + m[1]
-					matched = true
-				} else if m := jsArrowRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "ƒ " + m[1]
-					matched = true
-				} else if m := tsInterfaceRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "📄 " + m[1]
-					matched = true
-				} else if m := jsDescribeRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "🧪 " + m[1]
-					matched = true
-					closeChar = ")"
-				} else if m := jsItRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "✓ " + m[1]
-					matched = true
-					closeChar = ")"
-				} else if m := jsObjRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "🧱 " + m[1]
-					matched = true
-				} else if jsExportDefaultRe.MatchString(text) {
-					name = "📦 default export"
-					matched = true
-				} else if m := jsRouteRe.FindStringSubmatch(text); len(m) > 2 {
-					method := strings.ToUpper(m[1])
-					path := m[2]
-					name = "🛣️ " + method + " " + path
-					matched = true
-					closeChar = ")"
-				} else if m := jsMethodRe.FindStringSubmatch(text); len(m) > 1 {
-					candidate := m[1]
-					if !jsKeywords[candidate] {
-						name = "ƒ " + candidate
-						matched = true
-					}
-				} else if m := jsPropFuncRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "ƒ " + m[1]
-					matched = true
-				} else if m := jsPropArrowRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "ƒ " + m[1]
-					matched = true
-				}
-			} else if ext == ".java" || ext == ".cs" {
-				if m := javaClassRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "📦 " + m[1]
-					matched = true
-				} else if m := javaMethodRe.FindStringSubmatch(text); len(m) > 1 {
-					name = "ƒ " + m[1]
-					matched = true
-				}
-			} else if ext == ".css" || ext == ".scss" || ext == ".less" {
-				if strings.Contains(text, "{") {
-					if m := cssRe.FindStringSubmatch(strings.TrimSpace(text)); len(m) > 1 {
-						name = "🎨 " + strings.TrimSpace(m[1])
-						matched = true
-					}
-				}
-			}
-		} else if isPython {
-			if m := pyDefRe.FindStringSubmatch(text); len(m) > 1 {
-				indentStr := m[1]
-				name = "ƒ " + m[2]
-				matched = true
-				newIndent = len(indentStr)
-			} else if m := pyClassRe.FindStringSubmatch(text); len(m) > 1 {
-				indentStr := m[1]
-				name = "📦 " + m[2]
-				matched = true
-				newIndent = len(indentStr)
-			}
-		} else if ext == ".md" {
-			if m := mdRe.FindStringSubmatch(text); len(m) > 1 {
-				name = m[2]
-				regions = append(regions, Region{Start: lineNum, End: lineNum, Name: name})
-				continue
-			}
-		}
-
-		// Check for Dependencies
-		depName := ""
-		if ext == ".go" && depGoRe.MatchString(text) {
-			m := depGoRe.FindStringSubmatch(text)
-			if len(m) > 1 {
-				depName = m[1]
-			}
-		} else if (ext == ".js" || ext == ".ts" || ext == ".jsx" || ext == ".tsx") && depJsRe.MatchString(text) {
-			m := depJsRe.FindStringSubmatch(text)
-			if len(m) > 1 {
-				depName = m[1]
-			}
-		} else if isHtml {
-			if m := depHtmlScriptRe.FindStringSubmatch(text); len(m) > 1 {
-				depName = m[1]
-			} else if m := depHtmlLinkRe.FindStringSubmatch(text); len(m) > 1 {
-				if m[1] != "" {
-					depName = m[1]
-				} else {
-					depName = m[2]
-				}
-			}
-		} else if ext == ".css" && depCssRe.MatchString(text) {
-			m := depCssRe.FindStringSubmatch(text)
-			if len(m) > 1 {
-				depName = m[1]
-			}
-		}
 
-		if depName != "" {
-			regions = append(regions, Region{Start: lineNum, End: lineNum, Name: "🔗 depends on: " + depName})
-			// Do not mark as 'matched' for Scope stack, since dependencies are single lines
+	base := outputPath(cfg, path)
+	if base != path {
+		if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
 		}
+	}
 
-		if isHtml {
-			// HTML Tag Matching
-			structuralTags := map[string]bool{
-				"body": true, "div": true, "section": true, "article": true,
-				"header": true, "footer": true, "nav": true, "main": true,
-				"script": true, "style": true, "template": true,
-			}
-
-			if m := htmlTagRe.FindStringSubmatch(text); len(m) > 1 {
-				openTag := m[1]
-
-				if openTag != "" {
-					// Opening Tag found
-					lowerTag := strings.ToLower(openTag)
-					if structuralTags[lowerTag] {
-						// Extract ID or Class for nicer name
-						name = "<" + openTag + ">"
-						idRe := regexp.MustCompile(`id=["']([^"']+)["']`)
-						classRe := regexp.MustCompile(`class=["']([^"']+)["']`)
-
-						idMatch := idRe.FindStringSubmatch(text)
-						classMatch := classRe.FindStringSubmatch(text)
-
-						if len(idMatch) > 1 {
-							name += " #" + idMatch[1]
-						} else if len(classMatch) > 1 {
-							fields := strings.Fields(classMatch[1])
-							if len(fields) > 0 {
-								name += " ." + fields[0]
-							}
-						}
-
-						matched = true
-						tagName = lowerTag
-						closeChar = "</" + tagName + ">" // Not used for logic but logical
-					}
-				}
-			}
+	// Always generate a map, and drop the one of a format no longer written
+	if cfg.WritesText() {
+		mapPath := base + TextMapSuffix
+		if err := os.WriteFile(mapPath, []byte(fm.Text()), 0644); err != nil {
+			return fmt.Errorf("failed to write map %s: %w", mapPath, err)
 		}
-
-		if matched {
-			// PUSH NEW SCOPE
-			startParam := 0
-			if isBraceLang {
-				if closeChar == "}" {
-					startParam = braceLevel
-					if strings.Contains(text, "{") {
-						startParam--
-					}
-				} else if closeChar == ")" {
-					startParam = parenLevel
-					if strings.Contains(text, "(") {
-						startParam--
-					}
-				}
-			} else if isPython {
-				startParam = newIndent
-			}
-
-			// Determine Parent Context
-			var parentName string
-			for i := len(scopeStack) - 1; i >= 0; i-- {
-				s := scopeStack[i]
-				// Check if parent is Class, Object, or Interface
-				if strings.Contains(s.Region.Name, "📦") || strings.Contains(s.Region.Name, "🧱") || strings.Contains(s.Region.Name, "📄") {
-					// Extract clean name
-					raw := s.Region.Name
-					// Remove emoji and spaces
-					parts := strings.Fields(raw)
-					if len(parts) > 1 {
-						parentName = parts[1]
-					}
-					break
-				}
-			}
-
-			// If Method/Prop inside Class/Obj, prefix it
-			if parentName != "" && (strings.HasPrefix(name, "ƒ") || strings.HasPrefix(name, "✓")) {
-				// Strip icon from current name
-				cleanCurrent := strings.TrimSpace(name[len("ƒ "):])
-				if strings.HasPrefix(name, "✓") {
-					cleanCurrent = strings.TrimSpace(name[len("✓ "):])
-					name = "✓ " + parentName + " » " + cleanCurrent
-				} else {
-					name = "ƒ " + parentName + "." + cleanCurrent
-				}
-			}
-
-			scopeStack = append(scopeStack, Scope{
-				Region:    Region{Start: lineNum, Name: name},
-				OpenLevel: startParam,
-				Indent:    startParam,
-				CloseChar: closeChar,
-				Tag:       tagName,
-			})
+	} else {
+		os.Remove(base + TextMapSuffix)
+	}
+	if cfg.WritesJSON() {
+		mapPath := base + JSONMapSuffix
+		if err := writeJSON(mapPath, fm); err != nil {
+			return fmt.Errorf("failed to write map %s: %w", mapPath, err)
 		}
+	} else {
+		os.Remove(base + JSONMapSuffix)
 	}
+	return nil
+}
 
-	// Close remaining scopes at end of file
-	for i := len(scopeStack) - 1; i >= 0; i-- {
-		scope := &scopeStack[i]
-		scope.Region.End = lineNum
-		regions = append(regions, scope.Region)
+// BuildFileMap parses the file at path and returns its map without
+// writing anything.
+func BuildFileMap(path string) (*FileMap, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
 
-	// Always generate a map
-	mapPath := path + ".map.txt"
-	var sb strings.Builder
-
-	// --- METADATA HEADER ---
-	sb.WriteString(fmt.Sprintf("File: %s\n", filepath.Base(path)))
-	sb.WriteString(fmt.Sprintf("Path: %s\n", path))
-	sb.WriteString(fmt.Sprintf("Size: %.2f KB\n", sizeKB))
-	sb.WriteString(fmt.Sprintf("LOC: %d\n", lineNum))
-	sb.WriteString(fmt.Sprintf("Modified: %s\n", modTime))
-	sb.WriteString("--------------------------------------------------\n")
+	// Metadata
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	parser := ParserFor(ext)
+	if parser == nil {
+		parser = fallbackParser
+	}
+	regions := parser.Parse(src)
+	lineNum := countLines(src)
 
 	// Sort regions by Start line
 	sort.Slice(regions, func(i, j int) bool {
@@ -472,8 +106,8 @@ func GenerateMap(path string) {
 
 	// Post-Process: Extend single-line markers (like Headers or // Comments) to cover the block
 	for i := 0; i < len(regions); i++ {
-		// Only extend if it looks like a point-marker (Start == End)
-		if regions[i].Start == regions[i].End {
+		// Only extend point-markers; one-line declarations and dependencies keep their exact range
+		if regions[i].Start == regions[i].End && regions[i].Kind == KindMarker {
 			if i < len(regions)-1 {
 				// Extend to next region's start - 1
 				nextStart := regions[i+1].Start
@@ -485,31 +119,78 @@ func GenerateMap(path string) {
 				regions[i].End = lineNum
 			}
 		}
+		// Ensure End >= Start (sanity check)
+		if regions[i].End < regions[i].Start {
+			regions[i].End = regions[i].Start
+		}
 	}
 
-	if len(regions) > 0 {
-		for _, r := range regions {
-			// Format: | Start | End | Name
-			// Ensure End >= Start (sanity check)
-			if r.End < r.Start {
-				r.End = r.Start
-			}
-			sb.WriteString(fmt.Sprintf("| %4d | %4d | %s\n", r.Start, r.End, r.Name))
-		}
-	} else {
-		// Default map for file without markers
-		sb.WriteString(fmt.Sprintf("| %4d | %4d | (Entire File)\n", 1, lineNum))
+	return newFileMap(path, info, lineNum, regions), nil
+}
+
+// countLines counts lines the same way bufio.Scanner does: a trailing
+// newline does not start a new, empty line.
+func countLines(src []byte) int {
+	if len(src) == 0 {
+		return 0
+	}
+	n := bytes.Count(src, []byte("\n"))
+	if src[len(src)-1] != '\n' {
+		n++
 	}
+	return n
+}
 
-	err = os.WriteFile(mapPath, []byte(sb.String()), 0644)
-	if err != nil {
-		log.Printf("❌ Failed to write map: %s (%v)", filepath.Base(mapPath), err)
+// CollectFiles walks the roots of cfg (see config.WalkRoots) and returns
+// every file with an allowed extension that is not ignored. Extensions match
+// in any case, as in ScanNew.
+func CollectFiles(cfg config.Config) []string {
+	var allFiles []string
+	for _, rc := range cfg.WalkRoots() {
+		ig := NewIgnores(cfg, rc.Path)
+		validExts := make(map[string]bool)
+		for _, ext := range rc.AllowedExts {
+			validExts[strings.ToLower(ext)] = true
+		}
+
+		filepath.Walk(rc.Path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			if info.IsDir() {
+				// Check against git / node_modules default ignores if not in config
+				if info.Name() == ".git" || info.Name() == "node_modules" {
+					return filepath.SkipDir
+				}
+				if ig.Skip(path, true) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			ext := strings.ToLower(filepath.Ext(path))
+			if validExts[ext] && !ig.Skip(path, false) {
+				allFiles = append(allFiles, path)
+			}
+			return nil
+		})
 	}
+	return allFiles
 }
 
 // GenerateFolderMaps generates aggregated maps for all folders in the workspace
-func GenerateFolderMaps(cfg config.Config, allFiles []string) {
+func GenerateFolderMaps(cfg config.Config, root string, allFiles []string) {
 	log.Println("📂 Generating Folder Maps (Covering ALL directories)...")
+	RefreshFolderMaps(cfg, root, allFiles, nil)
+}
+
+// RefreshFolderMaps regenerates the folder maps of the directories in dirty,
+// plus any directory whose file inventory differs from the one the manifest
+// at root recorded for its maps, and their parents. A nil dirty set means
+// all.
+func RefreshFolderMaps(cfg config.Config, root string, allFiles []string, dirty map[string]bool) {
+	m := LoadManifest(cfg, root)
 
 	// 1. Group watched files by Directory (for Level 1 lookup)
 	watchedMap := make(map[string]map[string]bool)
@@ -521,44 +202,66 @@ func GenerateFolderMaps(cfg config.Config, allFiles []string) {
 		watchedMap[dir][f] = true
 	}
 
-	// 2. Identify ALL directories to scan (from Roots)
+	// 2. Identify ALL directories to scan (from the same roots as CollectFiles)
 	targetDirs := make(map[string]bool)
-	for _, rc := range cfg.Roots {
+	digests := make(map[string]string)
+	for _, rc := range cfg.WalkRoots() {
 		absRoot, _ := filepath.Abs(rc.Path)
+		ig := NewIgnores(cfg, absRoot)
 		filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return nil
 			}
 			if info.IsDir() {
-				if fs.ShouldIgnore(path, info, rc.IgnoredDirs) {
+				if ig.Skip(path, true) {
 					return filepath.SkipDir
 				}
-				targetDirs[path] = true
+				if files, err := inventory(path, NewIgnores(cfg, path)); err == nil {
+					digests[path] = inventoryDigest(files)
+				}
+				switch {
+				case dirty == nil || dirty[path]:
+					targetDirs[path] = true
+				case m.folderChanged(path, digests[path]):
+					// Parents list the files and folders below them too
+					markDirty(targetDirs, absRoot, path)
+				}
 			}
 			return nil
 		})
 	}
 
-	// 3. Process
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, 10)
-
+	// 3. Process (folder maps only read file maps, so folders are independent)
+	dirs := make([]string, 0, len(targetDirs))
 	for dir := range targetDirs {
-		wg.Add(1)
-		semaphore <- struct{}{}
-		go func(d string) {
-			defer wg.Done()
-			defer func() { <-semaphore }()
-			writeLevelMaps(d, watchedMap[d], cfg)
-		}(dir)
-	}
-	wg.Wait()
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	forEach(dirs, cfg.WorkerCount(), func(_ int, d string) {
+		writeLevelMaps(d, watchedMap[d], cfg)
+		m.recordFolder(d, digests[d])
+	})
+	if cfg.Scope == "" {
+		m.pruneFolders(digests)
+	}
+	if err := m.Save(); err != nil {
+		log.Printf("❌ Failed to write manifest: %v", err)
+	}
 }
 
 func writeLevelMaps(dir string, watchedFiles map[string]bool, cfg config.Config) {
 	dirName := filepath.Base(dir)
 	nowStr := time.Now().Format(time.RFC3339)
 
+	ig := NewIgnores(cfg, dir)
+
+	// Folder maps go to the folder itself or to its mirror under cfg.OutDir
+	outDir := outputPath(cfg, dir)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		log.Printf("❌ Failed to create output directory: %v", err)
+		return
+	}
+
 	// --- LEVEL 0: INVENTORY (All Files) ---
 	var sb0 strings.Builder
 	sb0.WriteString(fmt.Sprintf("# LEVEL 0: INVENTORY - %s\n", dirName))
@@ -568,25 +271,14 @@ func writeLevelMaps(dir string, watchedFiles map[string]bool, cfg config.Config)
 	sb0.WriteString("Name | Size | LOC | Modified\n")
 	sb0.WriteString("---|---|---|---\n")
 
-	entries, err := os.ReadDir(dir)
-	if err == nil {
-		for _, e := range entries {
-			if e.IsDir() {
-				continue
-			}
-			if strings.HasSuffix(e.Name(), ".map.txt") || fs.ShouldIgnoreName(e.Name(), nil) {
-				continue
-			}
-			info, err := e.Info()
-			if err != nil {
-				continue
-			}
-			loc := fs.CountLines(filepath.Join(dir, e.Name()))
-			modTime := info.ModTime().Format("2006-01-02 15:04")
+	if files, err := inventory(dir, ig); err == nil {
+		for _, info := range files {
+			loc := fs.CountLines(filepath.Join(dir, info.Name()))
+			modTime := info.ModTime().Format(inventoryTime)
 			sb0.WriteString(fmt.Sprintf("%s | %d | %d | %s\n",
-				e.Name(), info.Size(), loc, modTime))
+				info.Name(), info.Size(), loc, modTime))
 		}
-		os.WriteFile(filepath.Join(dir, "_level_0.map.txt"), []byte(sb0.String()), 0644)
+		os.WriteFile(filepath.Join(outDir, "_level_0.map.txt"), []byte(sb0.String()), 0644)
 	}
 
 	// --- LEVEL 1: STRUCTURE (Watched Codes) ---
@@ -602,72 +294,19 @@ func writeLevelMaps(dir string, watchedFiles map[string]bool, cfg config.Config)
 	}
 	sort.Strings(sortedWatched)
 
+	index := FolderIndex{Dir: dir, Generated: time.Now(), Files: []FileMap{}}
 	for _, fPath := range sortedWatched {
-		fName := filepath.Base(fPath)
-		mapPath := fPath + ".map.txt"
-		if _, err := os.Stat(mapPath); os.IsNotExist(err) {
-			continue
-		}
-
-		content, err := os.ReadFile(mapPath)
+		fm, err := LoadFileMap(cfg, fPath)
 		if err != nil {
 			continue
 		}
+		index.Files = append(index.Files, *fm)
 
-		sb1.WriteString(fmt.Sprintf("### %s\n", fName))
-
-		lines := strings.Split(string(content), "\n")
-		var inHeader = true
-		for _, l := range lines {
-			l = strings.TrimSpace(l)
-			if l == "" {
-				continue
-			}
-			// Header Skipping Logic
-			if inHeader {
-				if strings.HasPrefix(l, "File:") || strings.HasPrefix(l, "Path:") || strings.HasPrefix(l, "Size:") || strings.HasPrefix(l, "LOC:") || strings.HasPrefix(l, "Modified:") {
-					continue
-				}
-				if strings.HasPrefix(l, "-----") {
-					inHeader = false
-					continue
-				}
-				// Format check
-				if strings.HasPrefix(l, "|") || (strings.Contains(l, "|") && unicode.IsDigit(rune(l[0]))) {
-					inHeader = false // New or old format detected
-				}
-			}
-
-			if inHeader {
-				continue
-			}
-
-			// Parse content lines for re-formatting (or just copy them?)
-			// Existing code parsed and re-formatted.
-			// New Format: | Start | End | Name
-			if strings.HasPrefix(l, "|") {
-				parts := strings.SplitN(l[1:], "|", 3)
-				if len(parts) >= 3 {
-					start := strings.TrimSpace(parts[0])
-					end := strings.TrimSpace(parts[1])
-					name := strings.TrimSpace(parts[2])
-					sb1.WriteString(fmt.Sprintf("| %4s | %4s | %s\n", start, end, name))
-					continue
-				}
-			}
-			// Fallback Old Format
-			parts := strings.SplitN(l, "|", 2)
-			if len(parts) == 2 {
-				name := strings.TrimSpace(parts[1])
-				rng := strings.TrimSpace(parts[0])
-				sb1.WriteString(fmt.Sprintf("| %4s | %4s | %s\n", rng, rng, name)) // Best effort for old format
-			} else {
-				sb1.WriteString(fmt.Sprintf("| %4s | %4s | %s\n", "?", "?", l))
-			}
-		}
+		sb1.WriteString(fmt.Sprintf("### %s\n", filepath.Base(fPath)))
+		fm.writeRegions(&sb1, "")
 		sb1.WriteString("\n")
 	}
-	os.WriteFile(filepath.Join(dir, "_level_1.map.txt"), []byte(sb1.String()), 0644)
+	os.WriteFile(filepath.Join(outDir, "_level_1.map.txt"), []byte(sb1.String()), 0644)
 
 	// --- LEVEL 2: HIERARCHY ---
 	var sb2 strings.Builder
@@ -678,17 +317,26 @@ func writeLevelMaps(dir string, watchedFiles map[string]bool, cfg config.Config)
 		if err != nil || !info.IsDir() || path == dir {
 			return nil
 		}
-		if fs.ShouldIgnoreName(info.Name(), nil) {
+		if fs.ShouldIgnoreName(info.Name(), nil) || ig.Skip(path, true) {
 			return filepath.SkipDir
 		}
 		rel, _ := filepath.Rel(dir, path)
 		level := strings.Count(rel, string(os.PathSeparator))
 		indent := strings.Repeat("  ", level)
 		sb2.WriteString(fmt.Sprintf("%s- 📁 %s/\n", indent, info.Name()))
+		if level == 0 {
+			index.Subdirs = append(index.Subdirs, info.Name())
+		}
 
 		return nil
 	})
-	os.WriteFile(filepath.Join(dir, "_level_2.map.txt"), []byte(sb2.String()), 0644)
+	os.WriteFile(filepath.Join(outDir, "_level_2.map.txt"), []byte(sb2.String()), 0644)
+
+	if cfg.WritesJSON() {
+		writeJSON(filepath.Join(outDir, IndexFile), index)
+	} else {
+		os.Remove(filepath.Join(outDir, IndexFile))
+	}
 
 	// --- LEVEL 3: DEEP STRUCTURE ---
 	var sb3 strings.Builder
@@ -699,7 +347,7 @@ func writeLevelMaps(dir string, watchedFiles map[string]bool, cfg config.Config)
 		if err != nil || path == dir {
 			return nil
 		}
-		if fs.ShouldIgnoreName(info.Name(), nil) {
+		if fs.ShouldIgnoreName(info.Name(), nil) || ig.Skip(path, info.IsDir()) {
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
@@ -712,35 +360,48 @@ func writeLevelMaps(dir string, watchedFiles map[string]bool, cfg config.Config)
 		if info.IsDir() {
 			sb3.WriteString(fmt.Sprintf("%s- 📁 %s/\n", indent, info.Name()))
 		} else {
-			mapPath := path + ".map.txt"
-			if _, err := os.Stat(mapPath); err == nil {
+			if fm, err := LoadFileMap(cfg, path); err == nil {
 				sb3.WriteString(fmt.Sprintf("%s- 📄 %s\n", indent, info.Name()))
-				content, err := os.ReadFile(mapPath)
-				if err == nil {
-					lines := strings.Split(string(content), "\n")
-					for _, l := range lines {
-						l = strings.TrimSpace(l)
-						parts := strings.SplitN(l, "|", 4) // Try pipe format
-						if len(parts) >= 3 && strings.HasPrefix(l, "|") {
-							// | Start | End | Name
-							// Let's rely on simple split
-							p := strings.Split(l, "|")
-							if len(p) >= 4 {
-								start := strings.TrimSpace(p[1])
-								end := strings.TrimSpace(p[2])
-								name := strings.TrimSpace(p[3])
-								sb3.WriteString(fmt.Sprintf("%s    | %4s | %4s | %s\n", indent, start, end, name))
-							}
-						}
-					}
-				}
-			} else if !strings.HasSuffix(info.Name(), ".map.txt") {
+				fm.writeRegions(&sb3, indent+"    ")
+			} else if !IsMapFile(info.Name()) {
 				sb3.WriteString(fmt.Sprintf("%s- %s\n", indent, info.Name()))
 			}
 		}
 		return nil
 	})
-	os.WriteFile(filepath.Join(dir, "_level_3.map.txt"), []byte(sb3.String()), 0644)
+	os.WriteFile(filepath.Join(outDir, "_level_3.map.txt"), []byte(sb3.String()), 0644)
+}
+
+// inventoryTime is the precision of the modification times in _level_0 maps.
+const inventoryTime = "2006-01-02 15:04"
+
+// inventory lists the files of dir that its _level_0 map shows: every file
+// but maps and ignored ones.
+func inventory(dir string, ig *Ignores) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	for _, e := range entries {
+		if e.IsDir() || IsMapFile(e.Name()) || fs.ShouldIgnoreName(e.Name(), nil) || ig.Skip(filepath.Join(dir, e.Name()), false) {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, info)
+		}
+	}
+	return files, nil
+}
+
+// inventoryDigest sums up the names, sizes and modification times of the
+// files of an inventory, to tell when a _level_0 map no longer matches them.
+func inventoryDigest(files []os.FileInfo) string {
+	h := sha256.New()
+	for _, info := range files {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", info.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // UpdateFolderMaps triggers generation for a specific directory
@@ -757,13 +418,10 @@ func UpdateFolderMaps(dir string, watchlist map[string]bool, cfg config.Config)
 	writeLevelMaps(dir, watchedInDir, cfg)
 }
 
+// findRoot returns the configured root containing dir.
 func findRoot(cfg config.Config, dir string) string {
-	dir = filepath.Clean(dir)
-	for _, r := range cfg.Roots {
-		root, _ := filepath.Abs(r.Path)
-		if strings.HasPrefix(strings.ToLower(dir), strings.ToLower(root)) {
-			return root
-		}
+	if rc, ok := cfg.RootOf(filepath.Clean(dir)); ok {
+		return rc.Path
 	}
 	// Fallback: If not found, use the first root or dir itself
 	if len(cfg.Roots) > 0 {
@@ -778,24 +436,28 @@ func ScanNew(cfg config.Config, existingWatchlist map[string]bool) []string {
 	log.Println("🔍 Scanning for NEW files only...")
 	var newFiles []string
 
-	for _, rc := range cfg.Roots {
+	for _, rc := range cfg.WalkRoots() {
 		absRoot, _ := filepath.Abs(rc.Path)
+		ig := NewIgnores(cfg, absRoot)
 		filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return nil
 			}
 			if info.IsDir() {
-				if fs.ShouldIgnore(path, info, rc.IgnoredDirs) {
+				if ig.Skip(path, true) {
 					return filepath.SkipDir
 				}
 				return nil
 			}
+			if ig.Skip(path, false) {
+				return nil
+			}
 
 			// Check Allowed Extension
 			ext := strings.ToLower(filepath.Ext(path))
 			isAllowed := false
 			for _, allowed := range rc.AllowedExts {
-				if allowed == ext {
+				if strings.EqualFold(allowed, ext) {
 					isAllowed = true
 					break
 				}
@@ -805,13 +467,13 @@ func ScanNew(cfg config.Config, existingWatchlist map[string]bool) []string {
 				normPath := strings.ToLower(filepath.Clean(path))
 				if !existingWatchlist[normPath] {
 					// Found a new file!
-					GenerateMap(path)
 					newFiles = append(newFiles, path)
 				}
 			}
 			return nil
 		})
 	}
+	newFiles = generateAll(cfg, newFiles)
 	log.Printf("✨ Found and mapped %d new files.", len(newFiles))
 	return newFiles
 }
@@ -825,36 +487,40 @@ func ScanExtension(cfg config.Config, targetExt string) []string {
 		targetExt = "." + targetExt
 	}
 
-	for _, rc := range cfg.Roots {
+	for _, rc := range cfg.WalkRoots() {
 		absRoot, _ := filepath.Abs(rc.Path)
+		ig := NewIgnores(cfg, absRoot)
 		filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return nil
 			}
 			if info.IsDir() {
-				if fs.ShouldIgnore(path, info, rc.IgnoredDirs) {
+				if ig.Skip(path, true) {
 					return filepath.SkipDir
 				}
 				return nil
 			}
+			if ig.Skip(path, false) {
+				return nil
+			}
 
 			ext := strings.ToLower(filepath.Ext(path))
 			if ext == targetExt {
-				GenerateMap(path)
 				scanned = append(scanned, path)
 			}
 			return nil
 		})
 	}
+	scanned = generateAll(cfg, scanned)
 	log.Printf("✨ Mapped %d files with extension %s.", len(scanned), targetExt)
 	return scanned
 }
 
 // ScanFull performs a deep clean and then scans all allowed files.
 func ScanFull(cfg config.Config) []string {
-	for _, rc := range cfg.Roots {
+	for _, rc := range cfg.WalkRoots() {
 		absRoot, _ := filepath.Abs(rc.Path)
-		DeepClean(absRoot)
+		DeepClean(cfg, absRoot, false)
 	}
 	// Pass empty watchlist to force re-scan of everything allowed
 	return ScanNew(cfg, make(map[string]bool))
@@ -0,0 +1,76 @@
+package mapper
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+func TestRefreshFolderMapsInventory(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	writeFile(t, filepath.Join(sub, "a.go"), "package sub\n")
+	writeFile(t, filepath.Join(sub, "README.md"), "# Sub\n")
+	cfg := config.Config{Roots: []config.RootConfig{{Path: root, AllowedExts: []string{".go"}}}}
+	files := CollectFiles(cfg)
+	Sync(cfg, root, files, false)
+	GenerateFolderMaps(cfg, root, files)
+
+	// Only unmapped files change, so the manifest reports no dirty directory
+	writeFile(t, filepath.Join(sub, "README.md"), "# Sub\n\nMore.\n")
+	RefreshFolderMaps(cfg, root, files, map[string]bool{})
+	data, err := os.ReadFile(filepath.Join(sub, "_level_0.map.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "README.md | 13 |") {
+		t.Errorf("_level_0 does not show the new size of README.md:\n%s", data)
+	}
+
+	// Same size and same minute: only the exact mtime tells
+	mtime := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(sub, "README.md"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	RefreshFolderMaps(cfg, root, files, map[string]bool{})
+	writeFile(t, filepath.Join(sub, "README.md"), "# Sub\nab\nc\nd\n")
+	if err := os.Chtimes(filepath.Join(sub, "README.md"), mtime, mtime.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	RefreshFolderMaps(cfg, root, files, map[string]bool{})
+	if data, err = os.ReadFile(filepath.Join(sub, "_level_0.map.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "README.md | 13 | 4 |") {
+		t.Errorf("_level_0 does not show the new line count of README.md:\n%s", data)
+	}
+
+	writeFile(t, filepath.Join(sub, "notes.txt"), "x\n")
+	RefreshFolderMaps(cfg, root, files, map[string]bool{})
+	for _, dir := range []string{sub, root} {
+		data, err := os.ReadFile(filepath.Join(dir, "_level_3.map.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "notes.txt") {
+			t.Errorf("_level_3 of %s does not list the new notes.txt:\n%s", dir, data)
+		}
+	}
+}
+
+func TestCollectFilesExtensionCase(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.go", "B.GO", "c.Py", "d.txt"} {
+		writeFile(t, filepath.Join(root, name), "")
+	}
+	cfg := config.Config{Roots: []config.RootConfig{{Path: root, AllowedExts: []string{".go", ".PY"}}}}
+	got := relPaths(t, root, CollectFiles(cfg))
+	if want := []string{"B.GO", "a.go", "c.Py"}; !slices.Equal(got, want) {
+		t.Errorf("CollectFiles = %q, want %q", got, want)
+	}
+}
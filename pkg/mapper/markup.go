@@ -0,0 +1,90 @@
+package mapper
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdRe = regexp.MustCompile(`^(#+)\s+(.*)$`)
+
+	// HTML: Matches <tag ... > or </tag>
+	htmlTagRe   = regexp.MustCompile(`<\s*([a-zA-Z0-9-]+)\b([^>]*)>|<\s*/\s*([a-zA-Z0-9-]+)\s*>`)
+	htmlIDRe    = regexp.MustCompile(`id=["']([^"']+)["']`)
+	htmlClassRe = regexp.MustCompile(`class=["']([^"']+)["']`)
+
+	depHtmlScriptRe = regexp.MustCompile(`<\s*script\s+[^>]*src=["']([^"']+)["']`)
+	depHtmlLinkRe   = regexp.MustCompile(`<\s*link\s+[^>]*href=["']([^"']+)["'][^>]*rel=["']stylesheet["']|<\s*link\s+[^>]*rel=["']stylesheet["'][^>]*href=["']([^"']+)["']`)
+
+	htmlStructuralTags = map[string]bool{
+		"body": true, "div": true, "section": true, "article": true,
+		"header": true, "footer": true, "nav": true, "main": true,
+		"script": true, "style": true, "template": true,
+	}
+)
+
+// htmlParser maps HTML-like markup by its structural tags.
+var htmlParser = &lineParser{
+	style:   scopeTags,
+	detect:  detectHTML,
+	depends: htmlDepends,
+}
+
+func detectHTML(text string) (lineMatch, bool) {
+	m := htmlTagRe.FindStringSubmatch(text)
+	if len(m) < 2 || m[1] == "" {
+		return lineMatch{}, false
+	}
+	openTag := m[1]
+	lowerTag := strings.ToLower(openTag)
+	if !htmlStructuralTags[lowerTag] {
+		return lineMatch{}, false
+	}
+
+	// Extract ID or Class for nicer name
+	name := "<" + openTag + ">"
+	if idMatch := htmlIDRe.FindStringSubmatch(text); len(idMatch) > 1 {
+		name += " #" + idMatch[1]
+	} else if classMatch := htmlClassRe.FindStringSubmatch(text); len(classMatch) > 1 {
+		fields := strings.Fields(classMatch[1])
+		if len(fields) > 0 {
+			name += " ." + fields[0]
+		}
+	}
+	return lineMatch{Kind: KindElement, Name: name, Tag: lowerTag}, true
+}
+
+func htmlDepends(text string) string {
+	if m := depHtmlScriptRe.FindStringSubmatch(text); len(m) > 1 {
+		return m[1]
+	} else if m := depHtmlLinkRe.FindStringSubmatch(text); len(m) > 1 {
+		if m[1] != "" {
+			return m[1]
+		}
+		return m[2]
+	}
+	return ""
+}
+
+// parseMarkdown maps every "# Heading" to a section that runs until the next
+// heading (of any level) or the end of the file.
+func parseMarkdown(src []byte) []Region {
+	var regions []Region
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if m := mdRe.FindStringSubmatch(scanner.Text()); len(m) > 1 {
+			if n := len(regions); n > 0 {
+				regions[n-1].End = lineNum - 1
+			}
+			regions = append(regions, Region{Start: lineNum, End: lineNum, Kind: KindHeader, Name: m[2]})
+		}
+	}
+	if n := len(regions); n > 0 {
+		regions[n-1].End = lineNum
+	}
+	return regions
+}
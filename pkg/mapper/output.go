@@ -0,0 +1,130 @@
+package mapper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+// OutputDir returns the directory that mirrors root when cfg writes maps out
+// of tree, or "" when maps live next to the sources. An output directory
+// outside the root may be shared by several roots, so each root mirrors into
+// a folder of its own there, named after it.
+func OutputDir(cfg config.Config, root string) string {
+	out := outBase(cfg, root)
+	if out == "" || config.Within(out, root) {
+		return out
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(root)))
+	return filepath.Join(out, filepath.Base(root)+"-"+hex.EncodeToString(sum[:4]))
+}
+
+// outBase returns cfg.OutDir resolved against root.
+func outBase(cfg config.Config, root string) string {
+	if cfg.OutDir == "" {
+		return ""
+	}
+	if filepath.IsAbs(cfg.OutDir) {
+		return filepath.Clean(cfg.OutDir)
+	}
+	return filepath.Join(root, cfg.OutDir)
+}
+
+// CheckOutDir rejects an output directory that is a root or contains one:
+// maps would be written among the sources, and pruning the mirror of a
+// deleted folder would delete a real one.
+func CheckOutDir(cfg config.Config) error {
+	for _, rc := range cfg.Roots {
+		root, err := filepath.Abs(rc.Path)
+		if err != nil {
+			return err
+		}
+		if out := outBase(cfg, root); out != "" && config.Within(root, out) {
+			return fmt.Errorf("output directory %s must not be the root %s or contain it", out, root)
+		}
+	}
+	return nil
+}
+
+// outputPath returns where the maps of path (a source file or directory)
+// live: path itself, or its mirror below the output directory of its root.
+// File maps are outputPath + TextMapSuffix / JSONMapSuffix; folder maps sit
+// inside outputPath of the folder.
+func outputPath(cfg config.Config, path string) string {
+	if cfg.OutDir == "" {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	root := findRoot(cfg, abs)
+	out := OutputDir(cfg, root)
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.Join(out, rel)
+}
+
+// FolderMapPath returns the path of the folder map called name (such as
+// "_level_3.map.txt") of dir.
+func FolderMapPath(cfg config.Config, dir, name string) string {
+	return filepath.Join(outputPath(cfg, dir), name)
+}
+
+// isOutputDir reports whether path is the output directory of its root,
+// which walks over the source tree must not descend into.
+func isOutputDir(cfg config.Config, path string) bool {
+	out := OutputDir(cfg, findRoot(cfg, path))
+	return out != "" && filepath.Clean(path) == out
+}
+
+// pruneMirror removes the output mirror of dir, and of its ancestors, for as
+// long as they no longer exist in the source tree.
+func pruneMirror(cfg config.Config, dir string) {
+	for {
+		mirror := outputPath(cfg, dir)
+		if mirror == dir {
+			return
+		}
+		if _, err := os.Stat(dir); err == nil {
+			return
+		}
+		removeMirror(mirror, false)
+		dir = filepath.Dir(dir)
+	}
+}
+
+// removeMirror deletes the map files and manifests below mirror, then the
+// folders they leave empty, and returns the removed files. Anything else,
+// and the folders holding it, is kept: a mirror that is not one loses
+// nothing. With dryRun nothing is deleted.
+func removeMirror(mirror string, dryRun bool) []string {
+	var removed, dirs []string
+	filepath.Walk(mirror, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			dirs = append(dirs, path)
+		} else if IsMapFile(path) || info.Name() == ManifestFile {
+			if dryRun || os.Remove(path) == nil {
+				removed = append(removed, path)
+			}
+		}
+		return nil
+	})
+	if !dryRun {
+		// Deepest first; a folder that still holds something stays
+		for i := len(dirs) - 1; i >= 0; i-- {
+			os.Remove(dirs[i])
+		}
+	}
+	return removed
+}
@@ -0,0 +1,139 @@
+package mapper
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Parser extracts the structural regions of a single source file.
+//
+// Parse receives the raw file contents and returns its regions with 1-based,
+// inclusive line numbers. Regions do not need to be sorted; GenerateMap sorts
+// them and extends point markers before writing the map.
+type Parser interface {
+	Parse(src []byte) []Region
+}
+
+// ParserFunc adapts an ordinary function to the Parser interface.
+type ParserFunc func(src []byte) []Region
+
+// Parse calls f(src).
+func (f ParserFunc) Parse(src []byte) []Region {
+	return f(src)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Parser)
+)
+
+// fallbackParser is used for extensions without a registered parser. It only
+// picks up manual "// 1. Section" markers.
+var fallbackParser Parser = &lineParser{}
+
+// Register associates p with the given file extensions (".go", ".ts", ...).
+// Extensions are matched case-insensitively. Registering an extension that
+// already has a parser replaces it, so built-in languages can be overridden.
+func Register(p Parser, exts ...string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, ext := range exts {
+		registry[normalizeExt(ext)] = p
+	}
+}
+
+// ParserFor returns the parser registered for ext, or nil if there is none.
+func ParserFor(ext string) Parser {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[normalizeExt(ext)]
+}
+
+// RegisteredExts returns every extension with a registered parser, sorted.
+func RegisteredExts() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	exts := make([]string, 0, len(registry))
+	for ext := range registry {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// languages names the language of each built-in extension, for filtering.
+var languages = map[string]string{
+	".go":     "go",
+	".js":     "javascript",
+	".jsx":    "javascript",
+	".ts":     "typescript",
+	".tsx":    "typescript",
+	".java":   "java",
+	".cs":     "csharp",
+	".css":    "css",
+	".scss":   "css",
+	".less":   "css",
+	".py":     "python",
+	".rs":     "rust",
+	".c":      "c",
+	".h":      "c",
+	".cpp":    "cpp",
+	".cc":     "cpp",
+	".cxx":    "cpp",
+	".hpp":    "cpp",
+	".hh":     "cpp",
+	".hxx":    "cpp",
+	".html":   "html",
+	".htm":    "html",
+	".xml":    "xml",
+	".vue":    "vue",
+	".svelte": "svelte",
+	".astro":  "astro",
+	".php":    "php",
+	".md":     "markdown",
+}
+
+// languageAliases lets filters use common short names.
+var languageAliases = map[string]string{
+	"js": "javascript", "ts": "typescript", "py": "python",
+	"cs": "csharp", "c#": "csharp", "golang": "go", "md": "markdown", "rs": "rust",
+	"c++": "cpp", "cxx": "cpp",
+}
+
+// Language returns the language name of path based on its extension, or the
+// bare extension for files without a known language.
+func Language(path string) string {
+	ext := normalizeExt(filepath.Ext(path))
+	if lang, ok := languages[ext]; ok {
+		return lang
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+func init() {
+	Register(ParserFunc(parseGo), ".go")
+	Register(javascriptParser, ".js", ".jsx", ".ts", ".tsx")
+	Register(javaParser, ".java")
+	Register(csharpParser, ".cs")
+	Register(cssParser, ".css")
+	Register(scssParser, ".scss", ".less")
+	Register(ParserFunc(parsePython), ".py")
+	Register(rustParser, ".rs")
+	Register(&cParser{}, ".c", ".cpp", ".cc", ".cxx")
+	Register(&cParser{header: true}, ".h", ".hpp", ".hh", ".hxx")
+	Register(htmlParser, ".html", ".htm", ".xml", ".php")
+	Register(&sfcParser{flavor: "vue"}, ".vue")
+	Register(&sfcParser{flavor: "svelte"}, ".svelte")
+	Register(&sfcParser{flavor: "astro"}, ".astro")
+	Register(ParserFunc(parseMarkdown), ".md")
+}
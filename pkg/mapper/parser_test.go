@@ -0,0 +1,163 @@
+package mapper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// parserTest is a source file and the regions its map lists, each rendered
+// as "start-end label".
+type parserTest struct {
+	name, ext, src string
+	want           []string
+}
+
+// runParserTests maps every source the way a scan does and compares the
+// regions.
+func runParserTests(t *testing.T, tests []parserTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mapLines(t, tt.ext, tt.src); !slices.Equal(got, tt.want) {
+				t.Errorf("got regions\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// mapLines maps src as a file with extension ext and renders each region as
+// "start-end label".
+func mapLines(t *testing.T, ext, src string) []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sample"+ext)
+	writeFile(t, path, src)
+	fm, err := BuildFileMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, r := range fm.Regions {
+		lines = append(lines, fmt.Sprintf("%d-%d %s", r.Start, r.End, r.Label()))
+	}
+	return lines
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	p := ParserFunc(func(src []byte) []Region {
+		return []Region{{Start: 1, End: 1, Kind: KindMarker, Name: string(src)}}
+	})
+	Register(p, ".AstrmapTest")
+	if ParserFor(".astrmaptest") == nil || ParserFor("astrmaptest") == nil {
+		t.Fatal("extensions are not matched case-insensitively, with or without the dot")
+	}
+	if !slices.Contains(RegisteredExts(), ".astrmaptest") {
+		t.Errorf("RegisteredExts() = %v, want it to list .astrmaptest", RegisteredExts())
+	}
+	if got, want := mapLines(t, ".astrmaptest", "x"), []string{"1-1 📍 x"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if ParserFor(".nothing") != nil {
+		t.Error("ParserFor(.nothing) is not nil")
+	}
+}
+
+func TestParsers(t *testing.T) {
+	runParserTests(t, []parserTest{
+		{"JavaScript", ".js", "import { a } from './a'\n\nexport function run() {\n  return a\n}\n\nclass Box {\n  open() {\n    return `${1}`\n  }\n}\n\ndescribe('box', () => {\n  it('opens', () => {})\n})\n", []string{
+			"1-1 🔗 depends on: ./a",
+			"3-5 ƒ run",
+			"7-11 📦 Box",
+			"8-10 ƒ Box.open",
+			"13-15 🧪 box",
+			"14-14 ✓ box » opens",
+		}},
+		{"Java", ".java", `package a;
+
+import java.util.List;
+
+public class Repo {
+    public List<String> all() {
+        return null;
+    }
+}
+`, []string{
+			"5-9 📦 Repo",
+			"6-8 ƒ Repo.all",
+		}},
+		{"CSharp", ".cs", `using System;
+
+namespace App
+{
+    public class Repo
+    {
+        public void Save() { }
+    }
+}
+`, []string{
+			"5-8 📦 Repo",
+			"7-7 ƒ Repo.Save",
+		}},
+		{"CSS", ".css", `@import url("base.css");
+
+.box {
+  color: red;
+}
+
+@media (max-width: 600px) {
+  .box { color: blue; }
+}
+`, []string{
+			"1-1 🔗 depends on: base.css",
+			"3-5 🎨 .box",
+			"7-9 🎨 @media (max-width: 600px)",
+		}},
+		{"SCSS", ".scss", `.card {
+  // {
+  .title {
+    color: red;
+  }
+}
+`, []string{
+			"1-6 🎨 .card",
+			"3-5 🎨 .title",
+		}},
+		{"HTML", ".html", `<html>
+<head>
+  <link rel="stylesheet" href="a.css">
+</head>
+<body>
+  <main id="app">
+  </main>
+</body>
+</html>
+`, []string{
+			"3-3 🔗 depends on: a.css",
+			"5-8 <body>",
+			"6-7 <main> #app",
+		}},
+		{"Markdown", ".md", `# Title
+
+Text.
+
+## Usage
+
+More.
+`, []string{
+			"1-4 Title",
+			"5-7 Usage",
+		}},
+	})
+}
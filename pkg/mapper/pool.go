@@ -0,0 +1,66 @@
+package mapper
+
+import (
+	"log"
+	"sync"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+// forEach calls fn for every item on at most workers goroutines and returns
+// once all calls are done. fn gets the index of its item, so results stored
+// by index keep the order of items however the work was scheduled.
+func forEach[T any](items []T, workers int, fn func(i int, item T)) {
+	workers = min(max(workers, 1), len(items))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i, items[i])
+			}
+		}()
+	}
+	for i := range items {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// GenerateMaps maps files on cfg.WorkerCount() workers. errs[i] is the error
+// of files[i], nil when its map was written.
+func GenerateMaps(cfg config.Config, files []string) (errs []error) {
+	errs = make([]error, len(files))
+	forEach(files, cfg.WorkerCount(), func(i int, f string) {
+		errs[i] = GenerateMap(cfg, f)
+	})
+	return errs
+}
+
+// generateAll maps files in parallel, logs failures and returns the files
+// that were mapped.
+func generateAll(cfg config.Config, files []string) []string {
+	var mapped []string
+	for i, err := range GenerateMaps(cfg, files) {
+		if err != nil {
+			log.Printf("❌ %v", err)
+			continue
+		}
+		mapped = append(mapped, files[i])
+	}
+	return mapped
+}
+
+// CurrentFileMaps returns the current map of every file, like
+// CurrentFileMap, on cfg.WorkerCount() workers.
+func CurrentFileMaps(cfg config.Config, files []string) (maps []*FileMap, errs []error) {
+	maps = make([]*FileMap, len(files))
+	errs = make([]error, len(files))
+	forEach(files, cfg.WorkerCount(), func(i int, f string) {
+		maps[i], errs[i] = CurrentFileMap(cfg, f)
+	})
+	return maps, errs
+}
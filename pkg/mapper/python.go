@@ -0,0 +1,407 @@
+package mapper
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	pyDefRe   = regexp.MustCompile(`^(?:async\s+)?def\s+([A-Za-z_]\w*)`)
+	pyClassRe = regexp.MustCompile(`^class\s+([A-Za-z_]\w*)`)
+	pyConstRe = regexp.MustCompile(`^([A-Z][A-Z0-9_]+)\s*(?::[^=]*)?=[^=]`)
+	pyMainRe  = regexp.MustCompile(`^if\s+(?:__name__\s*==\s*["']__main__["']|["']__main__["']\s*==\s*__name__)\s*:`)
+	// from .mod import name, from . import (a, b), import pkg.mod as m, other
+	pyFromRe   = regexp.MustCompile(`^from\s+(\.*[\w.]*)\s+import\s+(.*)$`)
+	pyImportRe = regexp.MustCompile(`^import\s+(.*)$`)
+)
+
+// pyLine is a logical line of Python source: one statement, with its
+// bracketed and backslash continuations joined.
+type pyLine struct {
+	start, end int // physical lines, 1-based
+	indent     int // in columns, a tab moving to the next multiple of 8
+	// text is the statement without comments; code is the same with the
+	// contents of string literals blanked, so both have the same length
+	text, code string
+	firstLine  int      // length of the part of text on the first physical line
+	comments   []string // comment lines right above the statement
+	doc        string   // when the statement is only a string: its value
+	isDoc      bool
+}
+
+// pyLogicalLines splits Python source into logical lines, the way the
+// Python tokenizer does: newlines inside brackets and strings, and escaped
+// ones, continue the statement. Blank and comment-only lines are left out.
+func pyLogicalLines(src []byte) []pyLine {
+	s := string(src)
+	var out []pyLine
+	var cur *pyLine
+	var text, code strings.Builder
+	var comments []string
+	depth, line := 0, 1
+	docEnd := -1 // end of the string literal the statement starts with
+
+	finish := func() {
+		cur.end = line
+		cur.text, cur.code = text.String(), code.String()
+		if cur.firstLine < 0 {
+			cur.firstLine = len(cur.text)
+		}
+		if docEnd >= 0 && strings.TrimSpace(cur.code[docEnd:]) == "" {
+			cur.isDoc = true
+		} else {
+			cur.doc = ""
+		}
+		out = append(out, *cur)
+		cur, depth, docEnd = nil, 0, -1
+		text.Reset()
+		code.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		if cur == nil {
+			// Start of a physical line outside any statement: measure its indent
+			col, j := 0, i
+			for ; j < len(s) && (s[j] == ' ' || s[j] == '\t' || s[j] == '\f' || s[j] == '\r'); j++ {
+				if s[j] == '\t' {
+					col = col/8*8 + 8
+				} else if s[j] == ' ' {
+					col++
+				}
+			}
+			if j >= len(s) {
+				break
+			}
+			switch s[j] {
+			case '\n':
+				comments = nil
+				i = j + 1
+				line++
+				continue
+			case '#':
+				end := strings.IndexByte(s[j:], '\n')
+				if end < 0 {
+					end = len(s) - j
+				}
+				comments = append(comments, strings.TrimRight(s[j:j+end], "\r"))
+				i = j + end
+				continue
+			}
+			cur = &pyLine{start: line, indent: col, comments: comments, firstLine: -1}
+			comments = nil
+			i = j
+		}
+
+		c := s[i]
+		switch {
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '\r':
+			i++
+		case c == '\\' && (strings.HasPrefix(s[i+1:], "\n") || strings.HasPrefix(s[i+1:], "\r\n")):
+			if cur.firstLine < 0 {
+				cur.firstLine = text.Len()
+			}
+			text.WriteByte(' ')
+			code.WriteByte(' ')
+			i = strings.IndexByte(s[i:], '\n') + i + 1
+			line++
+		case c == '\n':
+			if depth == 0 {
+				finish()
+				i++
+				line++
+				continue
+			}
+			if cur.firstLine < 0 {
+				cur.firstLine = text.Len()
+			}
+			text.WriteByte(' ')
+			code.WriteByte(' ')
+			i++
+			line++
+		case pyStringStart(s, i) >= 0:
+			end, value, closed := pyStringEnd(s, i)
+			if strings.TrimSpace(code.String()) == "" && docEnd < 0 && text.Len() == 0 {
+				cur.doc = value
+				docEnd = end - i
+			}
+			lit := s[i:end]
+			if n := strings.Count(lit, "\n"); n > 0 {
+				if cur.firstLine < 0 {
+					cur.firstLine = text.Len() + strings.IndexByte(lit, '\n')
+				}
+				line += n
+			}
+			text.WriteString(lit)
+			// A literal left open at the end of its line may be a lone quote
+			blank := len(lit) - 1
+			if closed {
+				blank--
+			}
+			code.WriteByte('"')
+			code.WriteString(strings.Repeat(" ", max(blank, 0)))
+			if closed {
+				code.WriteByte('"')
+			}
+			i = end
+		default:
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+			text.WriteByte(c)
+			code.WriteByte(c)
+			i++
+		}
+	}
+	if cur != nil {
+		finish()
+	}
+	return out
+}
+
+// pyStringStart returns the index of the opening quote of the string
+// literal starting at s[i], after a prefix such as r, b, f or rb, or -1 when
+// no literal starts there.
+func pyStringStart(s string, i int) int {
+	if i > 0 && isWordByte(s[i-1]) {
+		return -1
+	}
+	j := i
+	for j < len(s) && j-i < 2 && strings.IndexByte("rRbBuUfF", s[j]) >= 0 {
+		j++
+	}
+	if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+		return j
+	}
+	return -1
+}
+
+// pyStringEnd returns the index just past the string literal starting at
+// s[i], its value, and whether its closing quote was found. A single-quoted
+// literal not closed on its line ends there.
+func pyStringEnd(s string, i int) (end int, value string, closed bool) {
+	q := pyStringStart(s, i)
+	delim := s[q : q+1]
+	if strings.HasPrefix(s[q:], strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+	from := q + len(delim)
+	for k := from; k < len(s); k++ {
+		switch {
+		case s[k] == '\\':
+			k++
+		case strings.HasPrefix(s[k:], delim):
+			return k + len(delim), s[from:k], true
+		case s[k] == '\n' && len(delim) == 1:
+			return k, s[from:k], false
+		}
+	}
+	return len(s), s[from:], false
+}
+
+// pyHeaderEnd returns the index of the ":" ending the header of a compound
+// statement in code, or len(code).
+func pyHeaderEnd(code string) int {
+	depth := 0
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ':':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(code)
+}
+
+// parsePython maps a Python module from its logical lines. Classes,
+// functions (async ones too) and methods at module and class level are
+// regions spanning their decorators and body, documented by their docstring
+// or the comments above; nested classes are mapped, functions local to a
+// function are not. Module-level UPPER_CASE assignments are constants, and
+// an `if __name__ == "__main__":` block is a "__main__" marker.
+func parsePython(src []byte) []Region {
+	var regions []Region
+	type scope struct {
+		region int    // index in regions, or -1
+		indent int    // of the header
+		end    int    // last line of the body so far
+		class  string // the class whose body this is
+		local  bool   // a function body or the main block: definitions inside are not mapped
+	}
+	var stack []scope
+	docFor := -1 // the region awaiting its docstring
+
+	var decorators []string
+	decoStart := 0
+	var decoComments []string
+
+	for _, l := range pyLogicalLines(src) {
+		for len(stack) > 0 && l.indent <= stack[len(stack)-1].indent {
+			top := stack[len(stack)-1]
+			if top.region >= 0 {
+				regions[top.region].End = top.end
+			}
+			stack = stack[:len(stack)-1]
+		}
+		for k := range stack {
+			stack[k].end = l.end
+		}
+		if docFor >= 0 {
+			idx := docFor
+			docFor = -1
+			if l.isDoc && len(stack) > 0 && stack[len(stack)-1].region == idx {
+				regions[idx].Doc = commentText(strings.Split(l.doc, "\n"))
+				continue
+			}
+		}
+
+		code := strings.TrimSpace(l.code)
+		local, class := false, ""
+		for _, sc := range stack {
+			local = local || sc.local
+		}
+		if len(stack) > 0 {
+			class = stack[len(stack)-1].class
+		}
+
+		if deps := pythonDepends(code); deps != nil {
+			for _, dep := range deps {
+				regions = append(regions, Region{Start: l.start, End: l.end, Kind: KindImport, Name: dep})
+			}
+			decorators = nil
+			continue
+		}
+		if strings.HasPrefix(code, "@") {
+			if len(decorators) == 0 {
+				decoStart, decoComments = l.start, l.comments
+			}
+			decorators = append(decorators, pyDecorator(strings.TrimSpace(l.text)))
+			continue
+		}
+
+		def, cls := pyDefRe.FindStringSubmatch(code), pyClassRe.FindStringSubmatch(code)
+		if def == nil && cls == nil {
+			decorators = nil
+			switch {
+			case local:
+			case len(stack) == 0 && pyMainRe.MatchString(strings.TrimSpace(l.text)):
+				regions = append(regions, Region{Start: l.start, End: l.end, Kind: KindMarker, Name: "__main__"})
+				stack = append(stack, scope{region: len(regions) - 1, indent: l.indent, end: l.end, local: true})
+			case len(stack) == 0:
+				if m := pyConstRe.FindStringSubmatch(code); m != nil {
+					// The first line only: a table spanning many would crowd the
+					// map. Its "{" or ":" opens a value, not a body, so it stays
+					sig := strings.Join(strings.Fields(l.text[:l.firstLine]), " ")
+					if l.firstLine < len(l.text) {
+						sig += " ..."
+					}
+					regions = append(regions, Region{
+						Start: l.start, End: l.end, Kind: KindConst, Name: m[1], Exported: !strings.HasPrefix(m[1], "_"),
+						Signature: sig, Doc: commentText(l.comments),
+					})
+				}
+			}
+			continue
+		}
+
+		if local {
+			stack = append(stack, scope{region: -1, indent: l.indent, end: l.end, local: true})
+			decorators = nil
+			continue
+		}
+		region := Region{Start: l.start, End: l.end, Doc: commentText(l.comments)}
+		if len(decorators) > 0 {
+			region.Start, region.Doc = decoStart, commentText(decoComments)
+		}
+		header := strings.TrimSpace(l.text[:pyHeaderEnd(l.code)])
+		region.Signature = signature(strings.Join(append(decorators, header), " "))
+		decorators = nil
+
+		sc := scope{indent: l.indent, end: l.end}
+		if def != nil {
+			region.Kind, region.Name = KindFunction, def[1]
+			if class != "" {
+				region.Kind, region.Parent = KindMethod, class
+			}
+			sc.local = true
+		} else {
+			region.Kind, region.Name, region.Parent = KindClass, cls[1], class
+			sc.class = cls[1]
+		}
+		region.Exported = !strings.HasPrefix(region.Name, "_")
+		regions = append(regions, region)
+		sc.region = len(regions) - 1
+		stack = append(stack, sc)
+		docFor = sc.region
+	}
+
+	for _, sc := range stack {
+		if sc.region >= 0 {
+			regions[sc.region].End = sc.end
+		}
+	}
+	return regions
+}
+
+// pyDecorator shortens a long decorator to its name: the arguments of
+// @pytest.mark.parametrize(...) would crowd out the signature.
+func pyDecorator(text string) string {
+	if len(text) <= 80 {
+		return text
+	}
+	if i := strings.IndexByte(text, '('); i >= 0 {
+		return text[:i] + "(...)"
+	}
+	return text
+}
+
+// pythonDepends returns the modules an import statement imports, or nil
+// for other statements. "from . import a, b" imports the modules ".a" and
+// ".b".
+func pythonDepends(code string) []string {
+	if m := pyFromRe.FindStringSubmatch(code); m != nil {
+		if strings.Trim(m[1], ".") != "" {
+			return []string{m[1]}
+		}
+		var deps []string
+		for _, name := range pyImportNames(m[2]) {
+			if name != "*" {
+				deps = append(deps, m[1]+name)
+			}
+		}
+		if len(deps) == 0 {
+			deps = []string{m[1]}
+		}
+		return deps
+	}
+	if m := pyImportRe.FindStringSubmatch(code); m != nil {
+		return pyImportNames(m[1])
+	}
+	return nil
+}
+
+// pyImportNames returns the names of an import list, without parentheses
+// and "as" aliases: "(a as b, c)" gives a and c.
+func pyImportNames(list string) []string {
+	list = strings.Trim(strings.TrimSpace(list), "()")
+	var names []string
+	for _, part := range strings.Split(list, ",") {
+		if fields := strings.Fields(part); len(fields) > 0 {
+			names = append(names, fields[0])
+		}
+	}
+	return names
+}
@@ -0,0 +1,56 @@
+package mapper
+
+import "testing"
+
+func TestPythonUnterminatedString(t *testing.T) {
+	for _, src := range []string{
+		"def f():\n    return \"\n",
+		"def f():\n    return '",
+		"x = r\"\n",
+		"\"",
+		"'''",
+	} {
+		parsePython([]byte(src)) // must not panic
+	}
+
+	regions := parsePython([]byte("def f():\n    return \"\n"))
+	if len(regions) != 1 || regions[0].Name != "f" || regions[0].End != 2 {
+		t.Errorf("got %+v, want f at lines 1-2", regions)
+	}
+}
+
+func TestPythonNestedClassAndTable(t *testing.T) {
+	regions := parsePython([]byte("MAX = {\n    \"a\": 1,\n}\n\nclass A:\n    class Meta:\n        x = 1\n"))
+	if len(regions) != 3 {
+		t.Fatalf("got %+v, want MAX, A and Meta", regions)
+	}
+	if sig := regions[0].Signature; sig != "MAX = { ..." {
+		t.Errorf("MAX signature = %q, want %q", sig, "MAX = { ...")
+	}
+	if meta := regions[2]; meta.Name != "Meta" || meta.Parent != "A" {
+		t.Errorf("got %+v, want Meta nested in A", meta)
+	}
+}
+
+func TestPythonParser(t *testing.T) {
+	runParserTests(t, []parserTest{
+		{"Python", ".py", `import os
+
+LIMIT = 3
+
+class Repo:
+    def save(self):
+        pass
+
+@cache
+def load():
+    return os.getcwd()
+`, []string{
+			"1-1 🔗 depends on: os",
+			"3-3 🧱 LIMIT",
+			"5-7 📦 Repo",
+			"6-7 ƒ Repo.save",
+			"9-11 ƒ load",
+		}},
+	})
+}
@@ -0,0 +1,132 @@
+package mapper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+	"github.com/hubby247/astrmap/pkg/fs"
+)
+
+// QueryOptions selects regions across every map below a root.
+type QueryOptions struct {
+	Pattern string   // case-insensitive regexp matched against region names
+	Kinds   []string // region kinds or kind groups (func, type, route, test, header)
+	Langs   []string // languages, e.g. go, python, ts
+	Path    string   // glob matched against the slash path relative to the root
+}
+
+// Match is a region found by Query.
+type Match struct {
+	Path   string // slash path relative to the query root
+	Region Region
+}
+
+// QualifiedName returns "Parent.Name" for nested regions and Name otherwise.
+func (m Match) QualifiedName() string {
+	return qualifiedName(m.Region)
+}
+
+// kindGroups expands the filter names users type into region kinds.
+var kindGroups = map[string][]Kind{
+	"func": {KindFunction, KindMethod},
+	"type": {KindClass, KindInterface, KindType},
+	"test": {KindTest, KindSuite},
+	"var":  {KindVar, KindConst},
+}
+
+// Query searches the maps of every file below root. Dependency regions are
+// only returned when asked for by kind. Results are ordered by path, then line.
+func Query(cfg config.Config, root string, q QueryOptions) ([]Match, error) {
+	re, err := regexp.Compile("(?i)" + q.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	kinds := make(map[Kind]bool)
+	for _, k := range q.Kinds {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if group, ok := kindGroups[k]; ok {
+			for _, g := range group {
+				kinds[g] = true
+			}
+		} else if k != "" {
+			kinds[Kind(k)] = true
+		}
+	}
+	langs := make(map[string]bool)
+	for _, l := range q.Langs {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if alias, ok := languageAliases[l]; ok {
+			l = alias
+		}
+		if l != "" {
+			langs[l] = true
+		}
+	}
+
+	var matches []Match
+	ig := NewIgnores(cfg, root)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != root && (fs.ShouldIgnoreName(info.Name(), ig.names) || ig.Skip(path, info.IsDir())) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || IsMapFile(path) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if q.Path != "" && !fs.MatchGlob(q.Path, rel) && !fs.MatchGlob(q.Path, filepath.Base(path)) {
+			return nil
+		}
+		if len(langs) > 0 && !langs[Language(path)] {
+			return nil
+		}
+
+		fm, err := LoadFileMap(cfg, path)
+		if err != nil {
+			return nil // not mapped
+		}
+		for _, r := range fm.Regions {
+			if len(kinds) > 0 {
+				if !kinds[r.Kind] {
+					continue
+				}
+			} else if r.Kind == KindImport {
+				continue
+			}
+			m := Match{Path: rel, Region: r}
+			if re.MatchString(m.QualifiedName()) {
+				matches = append(matches, m)
+			}
+		}
+		return nil
+	})
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Path != matches[j].Path {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].Region.Start < matches[j].Region.Start
+	})
+	return matches, err
+}
+
+// ignoredDirsFor returns the configured ignores of the root containing path.
+func ignoredDirsFor(cfg config.Config, path string) []string {
+	if rc, ok := cfg.RootOf(path); ok {
+		return rc.IgnoredDirs
+	}
+	return nil
+}
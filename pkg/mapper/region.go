@@ -0,0 +1,231 @@
+package mapper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kind is what a region declares.
+type Kind string
+
+const (
+	KindFunction  Kind = "function"
+	KindMethod    Kind = "method" // a function with a Parent type or object
+	KindClass     Kind = "class"  // classes and Go struct types
+	KindInterface Kind = "interface"
+	KindType      Kind = "type" // other named types (Go func, map, alias...)
+	KindConst     Kind = "const"
+	KindVar       Kind = "var"
+	KindRoute     Kind = "route" // HTTP handlers such as app.get("/path")
+	KindSuite     Kind = "suite" // describe() blocks
+	KindTest      Kind = "test"
+	KindStyle     Kind = "style"   // CSS rule blocks
+	KindElement   Kind = "element" // structural HTML tags
+	KindHeader    Kind = "header"  // Markdown headings
+	KindImport    Kind = "import"
+	KindMarker    Kind = "marker" // manual "// 1. Setup" sections
+	KindImpl      Kind = "impl"   // Rust impl blocks, named after their type
+	KindModule    Kind = "module" // inline modules and namespaces
+	KindMacro     Kind = "macro"
+	KindProp      Kind = "prop"  // props a component takes
+	KindEvent     Kind = "event" // events a component emits
+)
+
+// Region is a structural part of a file, with 1-based inclusive lines.
+type Region struct {
+	Start int  `json:"start"`
+	End   int  `json:"end"`
+	Kind  Kind `json:"kind"`
+	// Name is the bare identifier, heading, tag or imported path. The
+	// grouped import block of a Go file has no name.
+	Name string `json:"name"`
+	// Parent is the enclosing class, type, object or suite, if any
+	Parent string `json:"parent,omitempty"`
+	// Form follows the name in every map, details or not: the type
+	// parameters of a Go declaration and the form of a Go type, like
+	// "[T any] (struct)" or " (alias of Foo)"
+	Form string `json:"form,omitempty"`
+	// Signature is the declaration as written, without its body
+	Signature string `json:"signature,omitempty"`
+	Exported  bool   `json:"exported,omitempty"`
+	// Doc is the comment documenting the declaration
+	Doc string `json:"doc,omitempty"`
+}
+
+// kindIcons are the icons regions are rendered with in text maps.
+var kindIcons = []struct {
+	kind Kind
+	icon string
+}{
+	{KindFunction, "ƒ"},
+	{KindMethod, "ƒ"},
+	{KindClass, "📦"},
+	{KindInterface, "📄"},
+	{KindType, "🏷️"},
+	{KindConst, "🧱"},
+	{KindVar, "🔨"},
+	{KindRoute, "🛣️"},
+	{KindSuite, "🧪"},
+	{KindTest, "✓"},
+	{KindStyle, "🎨"},
+	{KindMarker, "📍"},
+	{KindImpl, "🧩"},
+	{KindModule, "🗂️"},
+	{KindMacro, "🪄"},
+	{KindProp, "🎛️"},
+	{KindEvent, "📣"},
+}
+
+const (
+	depPrefix     = "🔗 depends on: "
+	importsLabel  = "📥 Imports"
+	parentMarker  = "."   // Parent.method
+	suiteMarker   = " » " // Suite » test
+	elementPrefix = "<"
+)
+
+// Label renders the region for text maps: an icon, then the name qualified
+// by its parent, and its form.
+func (r Region) Label() string {
+	switch r.Kind {
+	case KindImport:
+		if r.Name == "" {
+			return importsLabel
+		}
+		return depPrefix + r.Name
+	case KindHeader, KindElement:
+		return r.Name
+	}
+	name := r.Name
+	if r.Parent != "" {
+		sep := parentMarker
+		if r.Kind == KindTest || r.Kind == KindSuite {
+			sep = suiteMarker
+		}
+		name = r.Parent + sep + name
+	}
+	name += r.Form
+	for _, ki := range kindIcons {
+		if ki.kind == r.Kind {
+			return ki.icon + " " + name
+		}
+	}
+	return name
+}
+
+// parseLabel reads a region back from its rendered label. Signature,
+// Exported and Doc are not part of labels.
+func parseLabel(start, end int, label string) Region {
+	r := Region{Start: start, End: end}
+	switch {
+	case label == importsLabel:
+		r.Kind = KindImport
+		return r
+	case strings.HasPrefix(label, depPrefix):
+		r.Kind, r.Name = KindImport, strings.TrimPrefix(label, depPrefix)
+		return r
+	case strings.HasPrefix(label, elementPrefix):
+		r.Kind, r.Name = KindElement, label
+		return r
+	}
+	r.Kind, r.Name = KindHeader, label
+	for _, ki := range kindIcons {
+		if strings.HasPrefix(label, ki.icon) {
+			r.Kind = ki.kind
+			r.Name = strings.TrimSpace(strings.TrimPrefix(label, ki.icon))
+			break
+		}
+	}
+	switch r.Kind {
+	case KindFunction, KindMethod, KindClass, KindInterface, KindType:
+		// The form may hold dots of its own: "[T fmt.Stringer]"
+		if m := formRe.FindStringSubmatch(r.Name); m != nil {
+			r.Name, r.Form = m[1], m[2]+m[3]
+		}
+		if i := strings.LastIndex(r.Name, parentMarker); i > 0 {
+			r.Parent, r.Name = r.Name[:i], r.Name[i+1:]
+			if r.Kind == KindFunction {
+				r.Kind = KindMethod
+			}
+		}
+	case KindTest, KindSuite:
+		if parent, name, ok := strings.Cut(r.Name, suiteMarker); ok {
+			r.Parent, r.Name = parent, name
+		}
+	}
+	return r
+}
+
+// isScope reports whether regions of kind k can be the Parent of others.
+func (k Kind) isScope() bool {
+	return k == KindClass || k == KindInterface || k == KindConst || k == KindImpl || k == KindModule
+}
+
+// hasSignature reports whether regions of kind k are declarations worth a
+// signature.
+func (k Kind) hasSignature() bool {
+	switch k {
+	case KindFunction, KindMethod, KindClass, KindInterface, KindType, KindConst, KindVar, KindImpl, KindModule, KindMacro, KindProp, KindEvent:
+		return true
+	}
+	return false
+}
+
+// signature turns a declaration line into a signature: trimmed, on one
+// line, without the "{" or ":" that opens its body.
+func signature(text string) string {
+	s := strings.Join(strings.Fields(text), " ")
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(s, "{"), ":"))
+	s = strings.ReplaceAll(s, "( ", "(")
+	return strings.ReplaceAll(s, ", )", ")")
+}
+
+var (
+	// xmlDocTagRe matches the C# XML doc tags that wrap a description
+	xmlDocTagRe = regexp.MustCompile(`</?(?:summary|remarks|para|c|see[^>]*)/?>`)
+	// formRe splits a rendered name from its form: "Set[T any] (map)"
+	formRe = regexp.MustCompile(`^(.+?)(\[.+?\])?( \(.+\))?$`)
+	// sentenceEndRe matches the end of a first sentence
+	sentenceEndRe = regexp.MustCompile(`[.!?](?:\s|$)`)
+)
+
+// firstSentence returns the first sentence of a doc comment's description,
+// on one line. JSDoc "@tags" and C# <param> docs end the description.
+func firstSentence(doc string) string {
+	var desc []string
+	for _, l := range strings.Split(doc, "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "@") || strings.HasPrefix(l, "<param") || strings.HasPrefix(l, "<returns") || strings.HasPrefix(l, "<typeparam") {
+			break
+		}
+		if l == "" && len(desc) > 0 {
+			break // end of the first paragraph
+		}
+		desc = append(desc, l)
+	}
+	s := strings.Join(strings.Fields(xmlDocTagRe.ReplaceAllString(strings.Join(desc, " "), "")), " ")
+	if loc := sentenceEndRe.FindStringIndex(s); loc != nil {
+		s = s[:loc[0]+1]
+	}
+	return s
+}
+
+// commentText strips the comment markers of doc comment lines and joins
+// them.
+func commentText(lines []string) string {
+	var out []string
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		for _, p := range []string{"///", "//", "/**", "/*", "*/", "*", "#"} {
+			if strings.HasPrefix(l, p) {
+				l = strings.TrimSpace(l[len(p):])
+				break
+			}
+		}
+		l = strings.TrimSpace(strings.TrimSuffix(l, "*/"))
+		if l != "" || len(out) > 0 {
+			out = append(out, l)
+		}
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
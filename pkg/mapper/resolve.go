@@ -0,0 +1,93 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+// ErrNoRegion is returned by ResolveRegion when no region has the name.
+var ErrNoRegion = errors.New("no region")
+
+// CurrentFileMap returns the map of the file at path, reparsing the file in
+// memory when it has no map yet or was modified after its map was written.
+// A reparsed map carries signatures and docs only when cfg.Detail is set,
+// like the one a scan would write.
+func CurrentFileMap(cfg config.Config, path string) (*FileMap, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	fm, err := LoadFileMap(cfg, path)
+	// Text maps store the mtime with second precision
+	if err == nil && !info.ModTime().Truncate(1e9).After(fm.Modified) {
+		return fm, nil
+	}
+	fm, err = BuildFileMap(path)
+	if err == nil && !cfg.Detail {
+		fm.stripDetail()
+	}
+	return fm, err
+}
+
+// ResolveRegion finds the region called name in the file at path. name may be
+// qualified ("Server.Handle") or bare ("Handle"); exact matches win over
+// case-insensitive ones. An ambiguous name is an error listing the candidates.
+func ResolveRegion(cfg config.Config, path, name string) (Region, error) {
+	fm, err := CurrentFileMap(cfg, path)
+	if err != nil {
+		return Region{}, err
+	}
+
+	stages := []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		strings.EqualFold,
+	}
+	for _, equal := range stages {
+		var found []Region
+		for _, r := range fm.Regions {
+			if r.Kind == KindImport {
+				continue
+			}
+			for _, candidate := range regionNames(r) {
+				if equal(candidate, name) {
+					found = append(found, r)
+					break
+				}
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			var list []string
+			for _, r := range found {
+				list = append(list, fmt.Sprintf("  %d-%d %s", r.Start, r.End, qualifiedName(r)))
+			}
+			return Region{}, fmt.Errorf("%q is ambiguous in %s:\n%s", name, fm.File, strings.Join(list, "\n"))
+		}
+	}
+	return Region{}, fmt.Errorf("%w %q in %s", ErrNoRegion, name, fm.File)
+}
+
+// regionNames lists the names a region can be referred to by: its name,
+// with and without the parent.
+func regionNames(r Region) []string {
+	names := []string{r.Name}
+	if r.Parent != "" {
+		names = append(names, r.Parent+"."+r.Name)
+	}
+	return names
+}
+
+func qualifiedName(r Region) string {
+	if r.Parent != "" {
+		return r.Parent + "." + r.Name
+	}
+	return r.Name
+}
@@ -0,0 +1,111 @@
+package mapper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// rustVis matches an optional visibility, like pub or pub(crate)
+const rustVis = `(?:pub(?:\s*\([^)]*\))?\s+)?`
+
+var (
+	rustFnRe     = regexp.MustCompile(`^\s*` + rustVis + `(?:default\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+(?:"[^"]*"\s+)?)?fn\s+([A-Za-z_][A-Za-z0-9_]*)`)
+	rustStructRe = regexp.MustCompile(`^\s*` + rustVis + `(?:struct|union)\s+([A-Za-z_][A-Za-z0-9_]*)`)
+	rustEnumRe   = regexp.MustCompile(`^\s*` + rustVis + `enum\s+([A-Za-z_][A-Za-z0-9_]*)`)
+	rustTraitRe  = regexp.MustCompile(`^\s*` + rustVis + `(?:unsafe\s+)?(?:auto\s+)?trait\s+([A-Za-z_][A-Za-z0-9_]*)`)
+	rustImplRe   = regexp.MustCompile(`^\s*(?:unsafe\s+)?impl\b(.*)`)
+	rustModRe    = regexp.MustCompile(`^\s*` + rustVis + `mod\s+([A-Za-z_][A-Za-z0-9_]*)\s*[{;]`) // mod a { } or mod a; in a.rs
+	rustMacroRe  = regexp.MustCompile(`^\s*macro_rules!\s*([A-Za-z_][A-Za-z0-9_]*)`)
+
+	// #[test], #[tokio::test], #[async_std::test]...
+	rustTestAttrRe = regexp.MustCompile(`^#\[(?:[\w:]+::)?test\b`)
+	// use std::io::{self, Read}; pub use crate::a::b as c; extern crate foo;
+	depRustRe = regexp.MustCompile(`^\s*(?:` + rustVis + `use\s+(?:::)?|extern\s+crate\s+)([\w:]+)`)
+)
+
+// rustParser maps Rust sources. Functions in impl blocks and traits are
+// methods of their type, and #[test] functions are tests.
+var rustParser = &lineParser{
+	style:     scopeBraces,
+	syntax:    rustSyntax,
+	attribute: rustAttribute,
+	detect:    detectRust,
+	depends:   rustDepends,
+}
+
+func detectRust(text string) (lineMatch, bool) {
+	trimmed := strings.TrimSpace(text)
+	exported := strings.HasPrefix(trimmed, "pub ")
+	if m := rustFnRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindFunction, Name: m[1], Exported: exported}, true
+	} else if m := rustStructRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindClass, Name: m[1], Exported: exported}, true
+	} else if m := rustEnumRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindType, Name: m[1], Exported: exported}, true
+	} else if m := rustTraitRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindInterface, Name: m[1], Exported: exported}, true
+	} else if m := rustImplRe.FindStringSubmatch(text); len(m) > 1 {
+		if name := rustImplType(m[1]); name != "" {
+			return lineMatch{Kind: KindImpl, Name: name}, true
+		}
+	} else if m := rustModRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindModule, Name: m[1], Exported: exported}, true
+	} else if m := rustMacroRe.FindStringSubmatch(text); len(m) > 1 {
+		return lineMatch{Kind: KindMacro, Name: m[1]}, true
+	}
+	return lineMatch{}, false
+}
+
+// rustImplType returns the type an impl block is for, given what follows
+// "impl": "<T> Trait<T> for path::Type<T> where ... {" gives "Type".
+func rustImplType(rest string) string {
+	rest = strings.TrimSpace(rest)
+	// Skip the generic parameters of the impl itself
+	if strings.HasPrefix(rest, "<") {
+		depth := 0
+		for i, c := range rest {
+			if c == '<' {
+				depth++
+			} else if c == '>' {
+				if depth--; depth == 0 {
+					rest = rest[i+1:]
+					break
+				}
+			}
+		}
+	}
+	rest, _, _ = strings.Cut(rest, "{")
+	rest, _, _ = strings.Cut(rest, " where ")
+	if _, target, ok := strings.Cut(rest, " for "); ok {
+		rest = target
+	}
+	rest = strings.TrimSpace(rest)
+	for _, prefix := range []string{"&", "mut ", "dyn "} {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, prefix))
+	}
+	if i := strings.IndexAny(rest, "< \t"); i >= 0 {
+		rest = rest[:i]
+	}
+	if i := strings.LastIndex(rest, "::"); i >= 0 {
+		rest = rest[i+2:]
+	}
+	return rest
+}
+
+// rustAttribute recognizes attribute lines, which may stand between a doc
+// comment and its item.
+func rustAttribute(trimmed string) (ok, test bool) {
+	if !strings.HasPrefix(trimmed, "#[") {
+		return false, false
+	}
+	return true, rustTestAttrRe.MatchString(trimmed)
+}
+
+// rustDepends returns the path a use declaration imports, without the
+// braces or glob of grouped imports: "use a::b::{c, d};" gives "a::b".
+func rustDepends(text string) string {
+	if m := depRustRe.FindStringSubmatch(text); len(m) > 1 {
+		return strings.TrimSuffix(m[1], "::")
+	}
+	return ""
+}
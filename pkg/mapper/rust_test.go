@@ -0,0 +1,37 @@
+package mapper
+
+import "testing"
+
+func TestRustParser(t *testing.T) {
+	runParserTests(t, []parserTest{
+		{"Rust", ".rs", `use std::fmt;
+
+pub struct Point {
+    x: i32,
+}
+
+impl fmt::Display for Point {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        write!(f, "{}", self.x)
+    }
+}
+`, []string{
+			"1-1 🔗 depends on: std::fmt",
+			"3-5 📦 Point",
+			"7-11 🧩 Point",
+			"8-10 ƒ Point.fmt",
+		}},
+		{"Rust modules", ".rs", `mod net;
+pub mod util;
+
+mod tests {
+    fn helper() {}
+}
+`, []string{
+			"1-1 🗂️ net",
+			"2-2 🗂️ util",
+			"4-6 🗂️ tests",
+			"5-5 ƒ tests.helper",
+		}},
+	})
+}
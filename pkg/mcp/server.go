@@ -0,0 +1,147 @@
+// Package mcp serves AstrMap maps to LLM agents over the Model Context
+// Protocol: newline-delimited JSON-RPC 2.0 on stdin/stdout.
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+// ProtocolVersion is the MCP revision this server implements.
+const ProtocolVersion = "2024-11-05"
+
+// ServerVersion is reported to clients in serverInfo.
+const ServerVersion = "0.1.0"
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server answers MCP requests about the workspace below its root.
+type Server struct {
+	cfg  config.Config
+	root string
+
+	mu  sync.Mutex // serializes writes
+	out io.Writer
+}
+
+// NewServer returns a server for the workspace at root (an absolute path).
+func NewServer(cfg config.Config, root string) *Server {
+	return &Server{cfg: cfg, root: root}
+}
+
+// Serve reads requests from in and writes responses to out until in is
+// exhausted. Requests are handled one at a time, in order.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.reply(nil, nil, &rpcError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+		s.handle(req)
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handle(req request) {
+	// Notifications carry no id and get no response
+	isNotification := len(req.ID) == 0
+
+	if req.JSONRPC != "2.0" {
+		if !isNotification {
+			s.reply(req.ID, nil, &rpcError{Code: codeInvalidRequest, Message: "jsonrpc must be \"2.0\""})
+		}
+		return
+	}
+
+	var result any
+	var rerr *rpcError
+	switch req.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "astrmap", "version": ServerVersion},
+			"instructions": "Navigate the codebase with its maps: start with list-directory-map, " +
+				"drill into get-file-map, find symbols with search-symbols and fetch exact code with read-region.",
+		}
+	case "notifications/initialized", "notifications/cancelled":
+		return
+	case "ping":
+		result = map[string]any{}
+	case "tools/list":
+		result = map[string]any{"tools": toolList()}
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			rerr = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+			break
+		}
+		result, rerr = s.callTool(p.Name, p.Arguments)
+	default:
+		rerr = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+
+	if !isNotification {
+		s.reply(req.ID, result, rerr)
+	}
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rerr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := response{JSONRPC: "2.0", ID: id, Result: result, Error: rerr}
+	if rerr == nil && result == nil {
+		resp.Result = map[string]any{}
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("❌ Failed to encode response: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.out.Write(append(data, '\n'))
+}
@@ -0,0 +1,262 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/fs"
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// toolArgs holds the arguments of every tool; each tool reads its own.
+type toolArgs struct {
+	Path    string `json:"path"`
+	Level   *int   `json:"level"`
+	Pattern string `json:"pattern"`
+	Kind    string `json:"kind"`
+	Lang    string `json:"lang"`
+	Name    string `json:"name"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Context int    `json:"context"`
+}
+
+func prop(typ, desc string) map[string]any {
+	return map[string]any{"type": typ, "description": desc}
+}
+
+func schema(required []string, props map[string]any) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func toolList() []tool {
+	return []tool{
+		{
+			Name: "list-directory-map",
+			Description: "Return a folder map. Level 0: file inventory, 1: code structure of the folder's files, " +
+				"2: subdirectory tree, 3: code structure of everything below the folder.",
+			InputSchema: schema(nil, map[string]any{
+				"path":  prop("string", "Folder relative to the workspace root (default: the root)"),
+				"level": prop("integer", "Map level 0-3 (default 3)"),
+			}),
+		},
+		{
+			Name:        "get-file-map",
+			Description: "Return the map of one file: its regions with start and end lines.",
+			InputSchema: schema([]string{"path"}, map[string]any{
+				"path": prop("string", "File relative to the workspace root"),
+			}),
+		},
+		{
+			Name:        "search-symbols",
+			Description: "Search region names across all maps. Returns path:start-end name per match.",
+			InputSchema: schema([]string{"pattern"}, map[string]any{
+				"pattern": prop("string", "Case-insensitive regular expression"),
+				"kind":    prop("string", "Comma-separated kinds: func, type, route, test, header, ..."),
+				"lang":    prop("string", "Comma-separated languages: go, python, ts, ..."),
+				"path":    prop("string", "Only files matching this glob, e.g. 'pkg/**/*.go'"),
+			}),
+		},
+		{
+			Name:        "read-region",
+			Description: "Return the source lines of a region, by name (e.g. 'Server.Handle') or by line range.",
+			InputSchema: schema([]string{"path"}, map[string]any{
+				"path":    prop("string", "File relative to the workspace root"),
+				"name":    prop("string", "Region name, qualified or bare"),
+				"start":   prop("integer", "First line, when no name is given"),
+				"end":     prop("integer", "Last line, when no name is given (default: start)"),
+				"context": prop("integer", "Extra lines before and after the region"),
+			}),
+		},
+	}
+}
+
+// callTool runs a tool. Failures of the tool itself are reported in the
+// result with isError set, as MCP asks; only bad requests are RPC errors.
+func (s *Server) callTool(name string, raw json.RawMessage) (any, *rpcError) {
+	var args toolArgs
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	var text string
+	var err error
+	switch name {
+	case "list-directory-map":
+		text, err = s.listDirectoryMap(args)
+	case "get-file-map":
+		text, err = s.getFileMap(args)
+	case "search-symbols":
+		text, err = s.searchSymbols(args)
+	case "read-region":
+		text, err = s.readRegion(args)
+	default:
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", name)}
+	}
+
+	if err != nil {
+		return toolResult(err.Error(), true), nil
+	}
+	return toolResult(text, false), nil
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// resolve turns a tool path into an absolute path inside the workspace.
+func (s *Server) resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	abs := filepath.Clean(path)
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(s.root, filepath.FromSlash(path))
+	}
+	rel, err := filepath.Rel(s.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the workspace", path)
+	}
+	return abs, nil
+}
+
+// refresh brings the maps of the workspace up to date, so agents never read
+// maps older than the code.
+func (s *Server) refresh() {
+	allFiles := mapper.CollectFiles(s.cfg)
+	res := mapper.Sync(s.cfg, s.root, allFiles, false)
+	if err := res.Err(); err != nil {
+		log.Printf("❌ %v", err)
+	}
+	mapper.RefreshFolderMaps(s.cfg, s.root, allFiles, res.DirtyDirs)
+}
+
+func (s *Server) listDirectoryMap(args toolArgs) (string, error) {
+	dir, err := s.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	level := 3
+	if args.Level != nil {
+		level = *args.Level
+	}
+	if level < 0 || level > 3 {
+		return "", fmt.Errorf("level must be between 0 and 3, got %d", level)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", args.Path)
+	}
+
+	s.refresh()
+	data, err := os.ReadFile(mapper.FolderMapPath(s.cfg, dir, fmt.Sprintf("_level_%d.map.txt", level)))
+	if err != nil {
+		return "", fmt.Errorf("no folder map for %s (is it ignored?)", args.Path)
+	}
+	return string(data), nil
+}
+
+func (s *Server) getFileMap(args toolArgs) (string, error) {
+	if args.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	path, err := s.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	fm, err := mapper.CurrentFileMap(s.cfg, path)
+	if err != nil {
+		return "", err
+	}
+	return fm.Text(), nil
+}
+
+func (s *Server) searchSymbols(args toolArgs) (string, error) {
+	if args.Pattern == "" {
+		return "", fmt.Errorf("pattern is required")
+	}
+	s.refresh()
+	matches, err := mapper.Query(s.cfg, s.root, mapper.QueryOptions{
+		Pattern: args.Pattern,
+		Kinds:   fs.SplitList(args.Kind),
+		Langs:   fs.SplitList(args.Lang),
+		Path:    args.Path,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "No matches.", nil
+	}
+	var sb strings.Builder
+	for _, m := range matches {
+		sb.WriteString(fmt.Sprintf("%s:%d-%d %s\n", m.Path, m.Region.Start, m.Region.End, m.QualifiedName()))
+	}
+	return sb.String(), nil
+}
+
+func (s *Server) readRegion(args toolArgs) (string, error) {
+	if args.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	path, err := s.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+
+	start, end, label := args.Start, args.End, ""
+	if args.Name != "" {
+		r, err := mapper.ResolveRegion(s.cfg, path, args.Name)
+		if err != nil {
+			return "", err
+		}
+		start, end, label = r.Start, r.End, r.Label()
+	} else {
+		if end == 0 {
+			end = start
+		}
+		if start < 1 || end < start {
+			return "", fmt.Errorf("give a region name or a valid start/end line range")
+		}
+	}
+
+	from, to := start-args.Context, end+args.Context
+	if from < 1 {
+		from = 1
+	}
+	lines, err := fs.ReadLines(path, from, to)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s:%d-%d", filepath.ToSlash(args.Path), start, end))
+	if label != "" {
+		sb.WriteString(" " + label)
+	}
+	sb.WriteString("\n")
+	width := len(strconv.Itoa(from + len(lines) - 1))
+	for i, l := range lines {
+		sb.WriteString(fmt.Sprintf("%*d | %s\n", width, from+i, l))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hubby247/astrmap/pkg/config"
+)
+
+func TestWorkspaceConfinement(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "ws")
+	for name, content := range map[string]string{
+		"ws/main.go":      "package main\n\nfunc main() {}\n",
+		"secret.txt":      "password\n",
+		"ws-sibling/a.go": "package a\n",
+	} {
+		path := filepath.Join(base, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewServer(config.Config{Roots: []config.RootConfig{{Path: root, AllowedExts: []string{".go"}}}}, root)
+
+	tests := []struct {
+		path    string
+		outside bool
+	}{
+		{"main.go", false},
+		{"./main.go", false},
+		{"", false},
+		{filepath.Join(root, "main.go"), false},
+		{"../secret.txt", true},
+		{"sub/../../secret.txt", true},
+		{filepath.Join(base, "secret.txt"), true},
+		{"../ws-sibling/a.go", true},
+		{filepath.Join(base, "ws-sibling", "a.go"), true},
+	}
+	for _, tt := range tests {
+		raw, _ := json.Marshal(toolArgs{Path: tt.path, Start: 1, End: 1})
+		for _, tool := range []string{"read-region", "get-file-map"} {
+			result, rerr := s.callTool(tool, raw)
+			if rerr != nil {
+				t.Fatalf("%s %q: %v", tool, tt.path, rerr.Message)
+			}
+			res := result.(map[string]any)
+			text := res["content"].([]map[string]any)[0]["text"].(string)
+			refused := res["isError"] == true && strings.Contains(text, "outside the workspace")
+			if refused != tt.outside {
+				t.Errorf("%s %q: isError=%v %q, want refused=%v", tool, tt.path, res["isError"], text, tt.outside)
+			}
+			if strings.Contains(text, "password") {
+				t.Errorf("%s %q leaked a file outside the workspace", tool, tt.path)
+			}
+		}
+	}
+}
@@ -0,0 +1,383 @@
+// Package pack builds one map document of a workspace that fits a token
+// budget, keeping the most detail around a focus.
+package pack
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/config"
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+// ErrNoFocus is returned by Build when the focus names no file, folder or
+// symbol of the workspace.
+var ErrNoFocus = errors.New("focus not found")
+
+// Options configures Build.
+type Options struct {
+	Budget int    // maximum estimated tokens of the document
+	Focus  string // file, folder or symbol to keep in most detail; "" for none
+}
+
+// Result is a packed document.
+type Result struct {
+	Text     string   `json:"text"`
+	Tokens   int      `json:"tokens"` // estimated tokens of Text
+	Budget   int      `json:"budget"`
+	Files    int      `json:"files"`    // files in the workspace
+	Shown    int      `json:"shown"`    // files listed in Text
+	Detailed int      `json:"detailed"` // files listed with their regions
+	Focus    []string `json:"focus,omitempty"`
+}
+
+// Detail levels of a file in the document, from least to most detailed.
+const (
+	levelOmitted = iota - 1 // not listed, only counted
+	levelListed             // name and size
+	levelOutline            // top-level declarations
+	levelRegions            // every region
+	levelFull               // regions with signatures and docs
+)
+
+// entry is one file of the document.
+type entry struct {
+	rel      string // slash path relative to the root
+	dir      string
+	fm       *mapper.FileMap
+	distance int // folders between the file and the focus
+	level    int
+	cost     [levelFull + 1]int // tokens of the entry at each level
+}
+
+// folder tracks the files of one directory that are shown and omitted.
+type folder struct {
+	shown, omitted int
+	header         int // tokens of the "## dir/" line
+}
+
+// Tokens of an "- … N more files" line, and of the closing summary line
+const (
+	noteCost   = 8
+	footerCost = 16
+)
+
+// Build reads the maps of the files below root, parsing only the files
+// without a current one, and renders them in one document of at most
+// opts.Budget estimated tokens. Files start with full detail; while
+// the document is too large, the file whose level plus distance from the
+// focus is highest loses one level of detail, so details far from the focus
+// go first. Without a focus, deeper folders are trimmed first.
+func Build(cfg config.Config, root string, opts Options) (Result, error) {
+	files := mapper.CollectFiles(cfg)
+	sort.Strings(files)
+	maps, errs := mapper.CurrentFileMaps(cfg, files)
+
+	var entries []*entry
+	for i, f := range files {
+		if errs[i] != nil {
+			log.Printf("❌ %v", errs[i])
+			continue
+		}
+		rel, err := filepath.Rel(root, f)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		entries = append(entries, &entry{rel: rel, dir: path.Dir(rel), fm: maps[i], level: levelFull})
+	}
+
+	focus, err := resolveFocus(root, opts.Focus, entries)
+	if err != nil {
+		return Result{}, err
+	}
+	folders := make(map[string]*folder)
+	for _, e := range entries {
+		e.distance = distance(e, focus)
+		for level := levelListed; level <= levelFull; level++ {
+			e.cost[level] = EstimateTokens(renderEntry(e.fm, level))
+		}
+		if folders[e.dir] == nil {
+			folders[e.dir] = &folder{header: EstimateTokens(dirHeader(e.dir))}
+		}
+		folders[e.dir].shown++
+	}
+
+	// Upper bound of the document size, kept up to date while trimming
+	total := EstimateTokens(header(root, opts.Focus)) + footerCost
+	for _, e := range entries {
+		total += e.cost[e.level]
+	}
+	for _, f := range folders {
+		total += f.header
+	}
+
+	queue := append(trimQueue(nil), entries...)
+	heap.Init(&queue)
+	for total > opts.Budget && queue.Len() > 0 {
+		e := heap.Pop(&queue).(*entry)
+		f := folders[e.dir]
+		if e.level == levelListed {
+			total -= e.cost[levelListed]
+			f.shown--
+			f.omitted++
+			switch {
+			case f.shown == 0:
+				total -= f.header
+				if f.omitted > 1 {
+					total -= noteCost
+				}
+			case f.omitted == 1:
+				total += noteCost
+			}
+		} else {
+			total -= e.cost[e.level] - e.cost[e.level-1]
+		}
+		e.level--
+		if e.level > levelOmitted {
+			heap.Push(&queue, e)
+		}
+	}
+	if total > opts.Budget {
+		return Result{}, fmt.Errorf("a budget of %d tokens is too small for %s: even a bare summary needs %d", opts.Budget, root, total)
+	}
+
+	// The last demotion may have freed more than needed: give the room back
+	// to the listed files closest to the focus
+	closest := append([]*entry(nil), entries...)
+	sort.SliceStable(closest, func(i, j int) bool { return closest[i].distance < closest[j].distance })
+	for _, e := range closest {
+		for e.level >= levelListed && e.level < levelFull && total+e.cost[e.level+1]-e.cost[e.level] <= opts.Budget {
+			total += e.cost[e.level+1] - e.cost[e.level]
+			e.level++
+		}
+	}
+
+	res := Result{Budget: opts.Budget, Files: len(entries)}
+	for _, e := range entries {
+		if e.distance == 0 && len(focus.files) > 0 {
+			res.Focus = append(res.Focus, e.rel)
+		}
+		if e.level >= levelListed {
+			res.Shown++
+		}
+		if e.level >= levelOutline {
+			res.Detailed++
+		}
+	}
+	res.Text = render(root, opts.Focus, entries)
+	res.Tokens = EstimateTokens(res.Text)
+	return res, nil
+}
+
+// focusSet is what a focus resolved to: files, or a folder.
+type focusSet struct {
+	files map[string]bool // slash paths relative to the root
+	dirs  []string        // folders of the files, or the focused folder
+}
+
+// resolveFocus finds the files or folder named by focus: a path relative to
+// the root or the working directory, or else the name of a symbol, bare
+// ("Handle") or qualified ("Server.Handle").
+func resolveFocus(root, focus string, entries []*entry) (focusSet, error) {
+	var fs focusSet
+	if focus == "" {
+		return fs, nil
+	}
+
+	candidates := []string{filepath.Join(root, focus)}
+	if abs, err := filepath.Abs(focus); err == nil {
+		candidates = append(candidates, abs)
+	}
+	for _, c := range candidates {
+		info, err := os.Stat(c)
+		rel, relErr := filepath.Rel(root, c)
+		if err != nil || relErr != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			fs.dirs = []string{rel}
+		} else {
+			fs.files = map[string]bool{rel: true}
+			fs.dirs = []string{path.Dir(rel)}
+		}
+		return fs, nil
+	}
+
+	fs.files = make(map[string]bool)
+	for _, e := range entries {
+		for _, r := range e.fm.Regions {
+			if r.Kind == mapper.KindImport {
+				continue
+			}
+			if strings.EqualFold(r.Name, focus) || (r.Parent != "" && strings.EqualFold(r.Parent+"."+r.Name, focus)) {
+				fs.files[e.rel] = true
+				fs.dirs = append(fs.dirs, e.dir)
+				break
+			}
+		}
+	}
+	if len(fs.files) == 0 {
+		return fs, fmt.Errorf("%w: no file, folder or symbol named %q", ErrNoFocus, focus)
+	}
+	return fs, nil
+}
+
+// distance counts the folders between e and the focus. Files of the focus
+// are at 0, and their neighbors in the same folder at 1. Without a focus,
+// it is the depth of the file's folder.
+func distance(e *entry, focus focusSet) int {
+	if len(focus.dirs) == 0 {
+		return depth(e.dir)
+	}
+	if focus.files[e.rel] {
+		return 0
+	}
+	best := -1
+	for _, d := range focus.dirs {
+		if dist := treeDistance(e.dir, d) + 1; best < 0 || dist < best {
+			best = dist
+		}
+	}
+	return best
+}
+
+func depth(dir string) int {
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+// treeDistance counts the folders on the way from a to b.
+func treeDistance(a, b string) int {
+	split := func(d string) []string {
+		if d == "." {
+			return nil
+		}
+		return strings.Split(d, "/")
+	}
+	pa, pb := split(a), split(b)
+	common := 0
+	for common < len(pa) && common < len(pb) && pa[common] == pb[common] {
+		common++
+	}
+	return len(pa) + len(pb) - 2*common
+}
+
+// trimQueue orders entries by the level of detail they lose next.
+type trimQueue []*entry
+
+func (q trimQueue) Len() int      { return len(q) }
+func (q trimQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q trimQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	if sa, sb := a.level+a.distance, b.level+b.distance; sa != sb {
+		return sa > sb
+	}
+	if a.distance != b.distance {
+		return a.distance > b.distance
+	}
+	return a.rel > b.rel
+}
+func (q *trimQueue) Push(x any) { *q = append(*q, x.(*entry)) }
+func (q *trimQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+func header(root, focus string) string {
+	s := fmt.Sprintf("# Map of %s\n", filepath.Base(root))
+	if focus != "" {
+		s += fmt.Sprintf("Focus: %s\n", focus)
+	}
+	return s + "Regions are \"start-end name\"; details thin out away from the focus.\n"
+}
+
+func dirHeader(dir string) string {
+	if dir == "." {
+		return "\n## ./\n"
+	}
+	return "\n## " + dir + "/\n"
+}
+
+// renderEntry renders one file at a level of detail.
+func renderEntry(fm *mapper.FileMap, level int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "- %s · %d LOC\n", fm.File, fm.LOC)
+	if level < levelOutline {
+		return sb.String()
+	}
+	for _, r := range fm.Regions {
+		if r.Kind == mapper.KindImport {
+			continue
+		}
+		if level == levelOutline && (r.Parent != "" || r.Kind == mapper.KindMarker) {
+			continue
+		}
+		fmt.Fprintf(&sb, "  %d-%d %s\n", r.Start, r.End, r.Label())
+		if level == levelFull {
+			if r.Signature != "" {
+				fmt.Fprintf(&sb, "      %s\n", r.Signature)
+			}
+			if r.Doc != "" {
+				fmt.Fprintf(&sb, "      // %s\n", r.Doc)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// render writes the document: entries grouped by folder, with a count of
+// the files left out.
+func render(root, focus string, entries []*entry) string {
+	var sb strings.Builder
+	sb.WriteString(header(root, focus))
+
+	byDir := make(map[string][]*entry)
+	var dirs []string
+	for _, e := range entries {
+		if byDir[e.dir] == nil {
+			dirs = append(dirs, e.dir)
+		}
+		byDir[e.dir] = append(byDir[e.dir], e)
+	}
+	sort.Strings(dirs)
+
+	hiddenFiles, hiddenDirs := 0, 0
+	for _, dir := range dirs {
+		var shown []*entry
+		for _, e := range byDir[dir] {
+			if e.level > levelOmitted {
+				shown = append(shown, e)
+			}
+		}
+		omitted := len(byDir[dir]) - len(shown)
+		if len(shown) == 0 {
+			hiddenFiles += omitted
+			hiddenDirs++
+			continue
+		}
+		sb.WriteString(dirHeader(dir))
+		for _, e := range shown {
+			sb.WriteString(renderEntry(e.fm, e.level))
+		}
+		if omitted > 0 {
+			fmt.Fprintf(&sb, "- … %d more files\n", omitted)
+		}
+	}
+	if hiddenFiles > 0 {
+		fmt.Fprintf(&sb, "\n… %d more files in %d folders not shown.\n", hiddenFiles, hiddenDirs)
+	}
+	return sb.String()
+}
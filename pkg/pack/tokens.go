@@ -0,0 +1,34 @@
+package pack
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// EstimateTokens approximates how many tokens an LLM tokenizer splits s
+// into, without a vocabulary: words and numbers cost one token per four
+// characters, every other symbol one token, and whitespace nothing. It errs
+// on the high side for maps, which are dense with punctuation.
+func EstimateTokens(s string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'):
+			word++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			// Symbols, emoji and non-Latin letters
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,167 @@
+//go:build linux
+
+package watcher
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF
+
+// Inotify watches a tree with one inotify watch per directory.
+type Inotify struct {
+	file   *os.File
+	fd     int
+	skip   SkipFunc
+	events chan Event
+	errors chan error
+	done   chan struct{}
+	once   sync.Once
+
+	mu    sync.Mutex
+	paths map[int]string // watch descriptor -> directory
+}
+
+func newNative(root string, skip SkipFunc) (Watcher, error) {
+	return NewInotify(root, skip)
+}
+
+// NewInotify starts watching root and every directory below it.
+func NewInotify(root string, skip SkipFunc) (*Inotify, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &Inotify{
+		// A non-blocking descriptor goes through the runtime poller, so
+		// Close unblocks the pending Read.
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		skip:   skip,
+		events: make(chan Event),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+		paths:  make(map[int]string),
+	}
+	if err := w.addTree(root, nil); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	go w.readLoop()
+	return w, nil
+}
+
+func (w *Inotify) Events() <-chan Event { return w.events }
+func (w *Inotify) Errors() <-chan error { return w.errors }
+
+// Close stops watching and closes the event channel.
+func (w *Inotify) Close() error {
+	// Nobody may read the events anymore: release a pending send
+	w.once.Do(func() { close(w.done) })
+	return w.file.Close()
+}
+
+// addTree watches dir and its subdirectories. When found is non-nil, files
+// already present are reported as created: they may have appeared before the
+// watch was in place.
+func (w *Inotify) addTree(dir string, found *[]Event) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != dir && w.skip(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			if found != nil {
+				*found = append(*found, Event{Path: path, Op: Create})
+			}
+			return nil
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			if path == dir && found == nil {
+				return os.NewSyscallError("inotify_add_watch", err)
+			}
+			return nil
+		}
+		w.mu.Lock()
+		w.paths[wd] = path
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+func (w *Inotify) readLoop() {
+	defer close(w.events)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return // closed
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(raw.Len)]
+			offset += syscall.SizeofInotifyEvent + int(raw.Len)
+
+			if raw.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				select {
+				case w.errors <- ErrOverflow:
+				default:
+				}
+				continue
+			}
+
+			w.mu.Lock()
+			dir, ok := w.paths[int(raw.Wd)]
+			if raw.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.paths, int(raw.Wd))
+			}
+			w.mu.Unlock()
+			if !ok {
+				continue
+			}
+
+			name := string(bytes.TrimRight(nameBytes, "\x00"))
+			if name == "" {
+				continue // event on the watched directory itself
+			}
+			path := filepath.Join(dir, name)
+			isDir := raw.Mask&syscall.IN_ISDIR != 0
+			if w.skip(path, isDir) {
+				continue
+			}
+
+			var out []Event
+			switch {
+			case raw.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+				out = append(out, Event{Path: path, Op: Remove})
+			case raw.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+				out = append(out, Event{Path: path, Op: Create})
+				if isDir {
+					w.addTree(path, &out)
+				}
+			case raw.Mask&(syscall.IN_CLOSE_WRITE|syscall.IN_MODIFY) != 0:
+				out = append(out, Event{Path: path, Op: Write})
+			}
+			for _, ev := range out {
+				select {
+				case w.events <- ev:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
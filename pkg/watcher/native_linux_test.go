@@ -0,0 +1,56 @@
+//go:build linux
+
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInotifyCloseWithPendingEvent(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewInotify(dir, func(string, bool) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("a"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first event shows the read loop runs; the others wait for a reader
+	select {
+	case <-w.Events():
+	case err := <-w.Errors():
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event for the new files")
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- w.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked on the pending events")
+	}
+
+	// The read loop gives up on the pending events and closes the channel
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-w.Events():
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("events not closed after Close")
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watcher
+
+import "errors"
+
+func newNative(root string, skip SkipFunc) (Watcher, error) {
+	return nil, errors.New("native file watching is not supported on this platform")
+}
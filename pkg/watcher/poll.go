@@ -0,0 +1,109 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// Poller detects changes by walking the tree at a fixed interval.
+type Poller struct {
+	root     string
+	skip     SkipFunc
+	interval time.Duration
+	events   chan Event
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewPoller starts polling root every interval.
+func NewPoller(root string, skip SkipFunc, interval time.Duration) (*Poller, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+	p := &Poller{
+		root:     root,
+		skip:     skip,
+		interval: interval,
+		events:   make(chan Event),
+		done:     make(chan struct{}),
+	}
+	go p.loop(p.snapshot())
+	return p, nil
+}
+
+func (p *Poller) Events() <-chan Event { return p.events }
+
+// Errors returns a nil channel: polling never loses events, and the next
+// walk retries what the last one could not read.
+func (p *Poller) Errors() <-chan error { return nil }
+
+// Close stops polling and closes the event channel.
+func (p *Poller) Close() error {
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
+
+func (p *Poller) snapshot() map[string]fileState {
+	state := make(map[string]fileState)
+	filepath.Walk(p.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != p.root && p.skip(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		state[path] = fileState{size: info.Size(), modTime: info.ModTime(), isDir: info.IsDir()}
+		return nil
+	})
+	return state
+}
+
+func (p *Poller) loop(prev map[string]fileState) {
+	defer close(p.events)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		cur := p.snapshot()
+		var batch []Event
+		for path, st := range cur {
+			old, ok := prev[path]
+			switch {
+			case !ok:
+				batch = append(batch, Event{Path: path, Op: Create})
+			case !st.isDir && (old.size != st.size || !old.modTime.Equal(st.modTime)):
+				batch = append(batch, Event{Path: path, Op: Write})
+			}
+		}
+		for path := range prev {
+			if _, ok := cur[path]; !ok {
+				batch = append(batch, Event{Path: path, Op: Remove})
+			}
+		}
+		prev = cur
+
+		for _, ev := range batch {
+			select {
+			case p.events <- ev:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package watcher reports file changes below a directory tree. On Linux it
+// uses inotify; everywhere else, or when inotify is unavailable, it polls.
+package watcher
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrOverflow is sent on Errors when the kernel dropped events. Callers
+// should rescan everything they care about.
+var ErrOverflow = errors.New("watcher: event queue overflowed, some changes were lost")
+
+// Op describes what happened to a path.
+type Op int
+
+const (
+	Create Op = iota + 1
+	Write
+	Remove
+)
+
+func (o Op) String() string {
+	switch o {
+	case Create:
+		return "create"
+	case Write:
+		return "write"
+	case Remove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// Event is a single change. Renames are reported as a Remove of the old path
+// followed by a Create of the new one.
+type Event struct {
+	Path string
+	Op   Op
+}
+
+// SkipFunc reports whether a path should not be watched or reported.
+// Skipped directories are not descended into.
+type SkipFunc func(path string, isDir bool) bool
+
+// Watcher delivers events for a directory tree until closed.
+type Watcher interface {
+	Events() <-chan Event
+	Errors() <-chan error
+	Close() error
+}
+
+// New watches root recursively with the best mechanism available.
+func New(root string, skip SkipFunc) (Watcher, error) {
+	if skip == nil {
+		skip = func(string, bool) bool { return false }
+	}
+	if w, err := newNative(root, skip); err == nil {
+		return w, nil
+	}
+	return NewPoller(root, skip, time.Second)
+}
+
+// Debounce groups events that arrive within quiet of each other into one
+// batch, keeping only the last event per path. The returned channel is closed
+// when events is.
+func Debounce(events <-chan Event, quiet time.Duration) <-chan []Event {
+	out := make(chan []Event)
+	go func() {
+		defer close(out)
+		pending := make(map[string]Event)
+		var order []string
+		var timer <-chan time.Time
+
+		flush := func() {
+			batch := make([]Event, 0, len(order))
+			for _, p := range order {
+				batch = append(batch, pending[p])
+			}
+			pending = make(map[string]Event)
+			order = nil
+			timer = nil
+			out <- batch
+		}
+
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					if len(order) > 0 {
+						flush()
+					}
+					return
+				}
+				if _, seen := pending[ev.Path]; !seen {
+					order = append(order, ev.Path)
+				}
+				pending[ev.Path] = ev
+				timer = time.After(quiet)
+			case <-timer:
+				flush()
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/hubby247/astrmap/pkg/fs"
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+func queryCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	kinds := flags.String("kind", "", "comma-separated kinds: func, type, route, test, header, ...")
+	langs := flags.String("lang", "", "comma-separated languages: go, python, ts, ...")
+	pathGlob := flags.String("path", "", "only files matching this glob (e.g. 'pkg/**/*.go')")
+	dir := flags.String("dir", ".", "workspace directory to search")
+	out := flags.String("out", "", "output directory holding the maps (default from config)")
+	return func(args []string) error {
+		if len(args) != 1 {
+			return usageError("expected exactly one pattern")
+		}
+		return runQuery(g, *dir, *out, args[0], *kinds, *langs, *pathGlob)
+	}
+}
+
+// queryMatch is one match as printed with --json.
+type queryMatch struct {
+	Path      string      `json:"path"`
+	Start     int         `json:"start"`
+	End       int         `json:"end"`
+	Kind      mapper.Kind `json:"kind"`
+	Name      string      `json:"name"`
+	Parent    string      `json:"parent,omitempty"`
+	Form      string      `json:"form,omitempty"`
+	Signature string      `json:"signature,omitempty"`
+}
+
+func runQuery(g *globals, dir, out, pattern, kinds, langs, pathGlob string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	cfg, err := g.loadConfig(absDir)
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		cfg.OutDir = out
+	}
+	if err := mapper.CheckOutDir(cfg); err != nil {
+		return err
+	}
+
+	if _, err := regexp.Compile(pattern); err != nil {
+		return usageError(fmt.Sprintf("invalid pattern: %v", err))
+	}
+	matches, err := mapper.Query(cfg, absDir, mapper.QueryOptions{
+		Pattern: pattern,
+		Kinds:   fs.SplitList(kinds),
+		Langs:   fs.SplitList(langs),
+		Path:    pathGlob,
+	})
+	if err != nil {
+		return err
+	}
+
+	results := make([]queryMatch, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, queryMatch{
+			Path: m.Path, Start: m.Region.Start, End: m.Region.End,
+			Kind: m.Region.Kind, Name: m.Region.Name, Parent: m.Region.Parent,
+			Form: m.Region.Form, Signature: m.Region.Signature,
+		})
+	}
+	err = g.emit(results, func() {
+		for _, m := range matches {
+			path := filepath.Join(dir, filepath.FromSlash(m.Path))
+			fmt.Printf("%s:%d-%d %s\n", path, m.Region.Start, m.Region.End, m.QualifiedName())
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		if !g.quiet && !g.json {
+			fmt.Fprintln(os.Stderr, "No matches. (Has this directory been scanned? Try: astrmap scan)")
+		}
+		return errNoMatch
+	}
+	return nil
+}
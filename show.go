@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hubby247/astrmap/pkg/fs"
+	"github.com/hubby247/astrmap/pkg/mapper"
+)
+
+// lineRangeRe matches the ":120" or ":120-180" suffix of a show target.
+var lineRangeRe = regexp.MustCompile(`:(\d+)(?:-(\d+))?$`)
+
+// parseShowTarget splits "file#Name" or "file:120-180" into its parts.
+func parseShowTarget(target string) (path, name string, start, end int, err error) {
+	if i := strings.LastIndex(target, "#"); i > 0 {
+		return target[:i], target[i+1:], 0, 0, nil
+	}
+	if m := lineRangeRe.FindStringSubmatch(target); m != nil {
+		start, _ = strconv.Atoi(m[1])
+		end = start
+		if m[2] != "" {
+			end, _ = strconv.Atoi(m[2])
+		}
+		if start < 1 || end < start {
+			return "", "", 0, 0, fmt.Errorf("invalid line range in %q", target)
+		}
+		return strings.TrimSuffix(target, m[0]), "", start, end, nil
+	}
+	return "", "", 0, 0, fmt.Errorf("expected file#Name or file:start-end, got %q", target)
+}
+
+func showCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	context := flags.Int("context", 0, "extra lines to print before and after the region")
+	numbers := flags.Bool("n", false, "prefix every line with its line number")
+	out := flags.String("out", "", "output directory holding the maps (default from config)")
+	return func(args []string) error {
+		if len(args) != 1 {
+			return usageError("expected exactly one target")
+		}
+		return runShow(g, args[0], *out, *context, *numbers)
+	}
+}
+
+// shownRegion is what show prints with --json.
+type shownRegion struct {
+	Path  string   `json:"path"`
+	Start int      `json:"start"` // line number of Lines[0]
+	End   int      `json:"end"`
+	Name  string   `json:"name,omitempty"`
+	Lines []string `json:"lines"`
+}
+
+func runShow(g *globals, target, out string, context int, numbers bool) error {
+	path, name, start, end, err := parseShowTarget(target)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	if name != "" {
+		cfg, err := g.loadConfig(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if out != "" {
+			cfg.OutDir = out
+		}
+		if err := mapper.CheckOutDir(cfg); err != nil {
+			return err
+		}
+		r, err := mapper.ResolveRegion(cfg, path, name)
+		if errors.Is(err, mapper.ErrNoRegion) {
+			if !g.quiet {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			return errNoMatch
+		}
+		if err != nil {
+			return err
+		}
+		start, end = r.Start, r.End
+	}
+
+	from := max(start-context, 1)
+	lines, err := fs.ReadLines(path, from, end+context)
+	if err != nil {
+		return err
+	}
+	result := shownRegion{Path: path, Start: from, End: from + len(lines) - 1, Name: name, Lines: lines}
+	return g.emit(result, func() { printLines(lines, from, numbers) })
+}
+
+// printLines writes lines, numbering them from first when numbers is set.
+func printLines(lines []string, first int, numbers bool) {
+	width := len(strconv.Itoa(first + len(lines) - 1))
+	for i, l := range lines {
+		if numbers {
+			fmt.Printf("%*d | %s\n", width, first+i, l)
+		} else {
+			fmt.Println(l)
+		}
+	}
+}
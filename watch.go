@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/hubby247/astrmap/pkg/fs"
+	"github.com/hubby247/astrmap/pkg/mapper"
+	"github.com/hubby247/astrmap/pkg/watcher"
+)
+
+func watchCommand(flags *flag.FlagSet, g *globals) func([]string) error {
+	debounce := flags.Duration("debounce", 300*time.Millisecond, "quiet period before a burst of changes is processed")
+	poll := flags.Bool("poll", false, "poll the file system instead of using native events")
+	out := flags.String("out", "", "output directory for maps (default from config, else next to the sources)")
+	detail := flags.Bool("detail", false, "add signatures and doc comments to maps (default from config)")
+	return func(args []string) error {
+		if err := maxArgs(args, 1); err != nil {
+			return err
+		}
+		return runWatch(g, argOr(args, "."), *out, *debounce, *poll, *detail)
+	}
+}
+
+// watchUpdate is printed, one JSON object per line, for every processed
+// batch of changes with --json.
+type watchUpdate struct {
+	Mapped  int    `json:"mapped"`
+	Removed int    `json:"removed"`
+	Rescan  bool   `json:"rescan,omitempty"`
+	Time    string `json:"time"`
+}
+
+func runWatch(g *globals, targetDir, out string, debounce time.Duration, poll, detail bool) error {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	cfg, err := g.loadConfig(absTarget)
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		cfg.OutDir = out
+	}
+	if err := mapper.CheckOutDir(cfg); err != nil {
+		return err
+	}
+	if detail {
+		cfg.Detail = true
+	}
+
+	// Bring everything up to date before listening for changes
+	allFiles := mapper.CollectFiles(cfg)
+	res := mapper.Sync(cfg, absTarget, allFiles, false)
+	if err := res.Err(); err != nil {
+		log.Printf("❌ %v", err)
+	}
+	mapper.RefreshFolderMaps(cfg, absTarget, allFiles, res.DirtyDirs)
+	saveGraph(cfg, absTarget, allFiles)
+
+	watchlist := make(map[string]bool, len(allFiles))
+	for _, f := range allFiles {
+		watchlist[f] = true
+	}
+
+	// Never react to our own output (maps, manifest) or to ignored paths,
+	// but do react to changed ignore rules
+	ignores := mapper.NewIgnores(cfg, absTarget)
+	skip := func(path string, isDir bool) bool {
+		if !isDir && mapper.IsIgnoreFile(path) {
+			return false
+		}
+		if !isDir && (mapper.IsMapFile(path) || fs.ShouldIgnoreName(filepath.Base(path), nil)) {
+			return true
+		}
+		return ignores.Skip(path, isDir)
+	}
+
+	var w watcher.Watcher
+	if poll {
+		w, err = watcher.NewPoller(absTarget, skip, time.Second)
+	} else {
+		w, err = watcher.New(absTarget, skip)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot watch %s: %w", absTarget, err)
+	}
+	defer w.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	g.infof("👀 Watching %s (%d files). Press Ctrl+C to stop.\n", absTarget, len(watchlist))
+	report := json.NewEncoder(os.Stdout)
+	manifest := mapper.LoadManifest(cfg, absTarget)
+
+	// rescan brings every map up to date from scratch; all folder maps are
+	// rewritten when dirty is nil
+	rescan := func(allDirs bool) {
+		allFiles := mapper.CollectFiles(cfg)
+		res := mapper.Sync(cfg, absTarget, allFiles, false)
+		if err := res.Err(); err != nil {
+			log.Printf("❌ %v", err)
+		}
+		dirty := res.DirtyDirs
+		if allDirs {
+			dirty = nil
+		}
+		mapper.RefreshFolderMaps(cfg, absTarget, allFiles, dirty)
+		saveGraph(cfg, absTarget, allFiles)
+		manifest = mapper.LoadManifest(cfg, absTarget)
+		clear(watchlist)
+		for _, f := range allFiles {
+			watchlist[f] = true
+		}
+	}
+	batches := watcher.Debounce(w.Events(), debounce)
+	for {
+		select {
+		case batch, ok := <-batches:
+			if !ok {
+				return nil
+			}
+			paths := make([]string, len(batch))
+			rulesChanged := false
+			for i, ev := range batch {
+				paths[i] = ev.Path
+				rulesChanged = rulesChanged || mapper.IsIgnoreFile(ev.Path)
+			}
+			if rulesChanged {
+				// Folders that were ignored when watching started stay
+				// unwatched until restart, but everything is remapped now
+				log.Println("🔁 Ignore rules changed, rescanning...")
+				ignores.Reset()
+				rescan(true)
+				if g.json {
+					report.Encode(watchUpdate{Rescan: true, Time: time.Now().Format(time.RFC3339)})
+				}
+				continue
+			}
+			start := time.Now()
+			mapped, removed := mapper.UpdateFiles(cfg, absTarget, manifest, watchlist, paths)
+			if mapped+removed > 0 {
+				saveGraph(cfg, absTarget, slices.Collect(maps.Keys(watchlist)))
+				if g.json {
+					report.Encode(watchUpdate{Mapped: mapped, Removed: removed, Time: time.Now().Format(time.RFC3339)})
+				}
+				g.infof("🔄 %d mapped, %d removed in %v\n", mapped, removed, time.Since(start))
+			}
+		case err := <-w.Errors():
+			// Events were lost: fall back to a full incremental scan
+			log.Printf("⚠️ %v", err)
+			rescan(false)
+		case <-interrupt:
+			g.infof("\n👋 Stopped watching.\n")
+			return nil
+		}
+	}
+}